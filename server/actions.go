@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	actionRebuild      = "rebuild"
+	actionAbort        = "abort"
+	actionGetLog       = "get-log"
+	actionTestResults  = "test-results"
+	actionGetArtifacts = "get-artifacts"
+)
+
+// buildAction creates a button which runs the given action on a build when clicked.
+func (p *Plugin) buildAction(name, action, jobName string, buildNumber int64) *model.PostAction {
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	return &model.PostAction{
+		Name: name,
+		Type: model.PostActionTypeButton,
+		Integration: &model.PostActionIntegration{
+			URL: fmt.Sprintf("%s/plugins/jenkins/action", siteURL),
+			Context: map[string]interface{}{
+				"action": action,
+				"job":    jobName,
+				"build":  strconv.FormatInt(buildNumber, 10),
+			},
+		},
+	}
+}
+
+// buildStartedAttachment creates an attachment announcing a started build, with buttons to abort it or get its log.
+func (p *Plugin) buildStartedAttachment(jobName string, build *gojenkins.Build) *model.SlackAttachment {
+	attachment := generateSlackAttachment(fmt.Sprintf("Job '%s' - #%d has been started\nBuild URL : %s", jobName, build.GetBuildNumber(), build.GetUrl()))
+	attachment.Actions = []*model.PostAction{
+		p.buildAction("Abort", actionAbort, jobName, build.GetBuildNumber()),
+		p.buildAction("Get log", actionGetLog, jobName, build.GetBuildNumber()),
+	}
+	return attachment
+}
+
+// followBuild posts that the build has started and follows it until it has finished.
+func (p *Plugin) followBuild(userID, channelID, jobName string, build *gojenkins.Build) {
+	p.createAttachmentPost(userID, channelID, jobInstance(jobName), p.buildStartedAttachment(jobName, build))
+	p.watchBuild(userID, channelID, jobName, build)
+}
+
+// handleAction runs the action of a button clicked on a build post, with the credentials of the user who clicked it.
+func (p *Plugin) handleAction(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if request.UserId != userID {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	action, _ := request.Context["action"].(string)
+	if action == actionConfirm || action == actionCancel {
+		p.handleConfirmationAction(w, userID, &request)
+		return
+	}
+
+	jobName, _ := request.Context["job"].(string)
+	buildID, _ := request.Context["build"].(string)
+	if jobName == "" {
+		http.Error(w, "Job name is missing", http.StatusBadRequest)
+		return
+	}
+
+	var response model.PostActionIntegrationResponse
+	if allowed, denial := p.checkCommandPermission(userID, request.ChannelId, actionCommand(action)); !allowed {
+		response.EphemeralText = denial
+		b, _ := json.Marshal(response)
+		_, _ = w.Write(b)
+		return
+	}
+
+	switch action {
+	case actionInputProceed, actionInputAbort:
+		// Input actions run right away, as proceeding an input with parameters opens a dialog which needs the trigger ID.
+		inputID, _ := request.Context["input"].(string)
+		if inputID == "" {
+			http.Error(w, "Input ID is missing", http.StatusBadRequest)
+			return
+		}
+
+		message, err := p.runInputAction(userID, request.ChannelId, request.TriggerId, action, jobName, buildID, inputID)
+		if err != nil {
+			p.API.LogError("Error running the input action", "action", action, "job_name", jobName, "build", buildID, "err", err.Error())
+			message = fmt.Sprintf("Encountered an error while submitting the input of the build #%s of the job '%s'.", buildID, jobName)
+		}
+		response.EphemeralText = message
+
+		b, _ := json.Marshal(response)
+		_, _ = w.Write(b)
+		return
+	case actionRebuild:
+		response.EphemeralText = fmt.Sprintf("Rebuilding the build #%s of the job '%s'...", buildID, jobName)
+	case actionAbort:
+		response.EphemeralText = fmt.Sprintf("Aborting the build #%s of the job '%s'...", buildID, jobName)
+	case actionGetLog:
+		response.EphemeralText = fmt.Sprintf("Fetching the log of the build #%s of the job '%s'...", buildID, jobName)
+	case actionTestResults:
+		response.EphemeralText = fmt.Sprintf("Fetching the test results of the build #%s of the job '%s'...", buildID, jobName)
+	case actionGetArtifacts:
+		response.EphemeralText = fmt.Sprintf("Fetching the artifacts of the build #%s of the job '%s'...", buildID, jobName)
+	default:
+		http.Error(w, "Unknown action", http.StatusBadRequest)
+		return
+	}
+
+	// Actions might take a while, so they run in the background to answer the request right away.
+	go func() {
+		if err := p.runBuildAction(userID, request.ChannelId, action, jobName, buildID); err != nil {
+			p.API.LogError("Error running the build action", "action", action, "job_name", jobName, "build", buildID, "err", err.Error())
+			p.createEphemeralPost(userID, request.ChannelId, fmt.Sprintf("Encountered an error while running '%s' on the build #%s of the job '%s'.", action, buildID, jobName))
+		}
+	}()
+
+	b, _ := json.Marshal(response)
+	_, _ = w.Write(b)
+}
+
+// runBuildAction runs an action on a build of the job.
+func (p *Plugin) runBuildAction(userID, channelID, action, jobName, buildID string) error {
+	switch action {
+	case actionRebuild:
+		build, err := p.rebuildJob(userID, channelID, jobName, buildID, nil)
+		if err != nil {
+			return err
+		}
+		p.followBuild(userID, channelID, jobName, build)
+	case actionAbort:
+		if err := p.abortBuild(userID, jobName, buildID); err != nil {
+			return err
+		}
+		p.createPost(userID, channelID, jobInstance(jobName), fmt.Sprintf("Build #%s of the job '%s' has been aborted.", buildID, jobName))
+	case actionGetLog:
+		return p.fetchAndUploadBuildLog(userID, channelID, jobName, buildID)
+	case actionTestResults:
+		return p.postBuildTestResults(userID, channelID, jobName, buildID)
+	case actionGetArtifacts:
+		return p.fetchAndUploadArtifactsOfABuild(userID, channelID, jobName, buildID, artifactOptions{})
+	default:
+		return errors.Errorf("unknown action %q", action)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/waseem18/gojenkins"
+)
+
+func TestHandleAction(t *testing.T) {
+	p := &Plugin{}
+	p.SetAPI(&plugintest.API{})
+
+	for name, tc := range map[string]struct {
+		UserID       string
+		Body         string
+		ExpectedCode int
+	}{
+		"missing user": {
+			Body:         `{"user_id": "user1", "context": {"action": "abort", "job": "jobname", "build": "42"}}`,
+			ExpectedCode: http.StatusUnauthorized,
+		},
+		"different user": {
+			UserID:       "user2",
+			Body:         `{"user_id": "user1", "context": {"action": "abort", "job": "jobname", "build": "42"}}`,
+			ExpectedCode: http.StatusUnauthorized,
+		},
+		"invalid body": {
+			UserID:       "user1",
+			Body:         `{`,
+			ExpectedCode: http.StatusBadRequest,
+		},
+		"missing job": {
+			UserID:       "user1",
+			Body:         `{"user_id": "user1", "context": {"action": "abort", "build": "42"}}`,
+			ExpectedCode: http.StatusBadRequest,
+		},
+		"missing input": {
+			UserID:       "user1",
+			Body:         `{"user_id": "user1", "context": {"action": "input-proceed", "job": "jobname", "build": "42"}}`,
+			ExpectedCode: http.StatusBadRequest,
+		},
+		"missing confirmation": {
+			UserID:       "user1",
+			Body:         `{"user_id": "user1", "context": {"action": "confirm"}}`,
+			ExpectedCode: http.StatusBadRequest,
+		},
+		"unknown action": {
+			UserID:       "user1",
+			Body:         `{"user_id": "user1", "context": {"action": "explode", "job": "jobname", "build": "42"}}`,
+			ExpectedCode: http.StatusBadRequest,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/action", strings.NewReader(tc.Body))
+			if tc.UserID != "" {
+				r.Header.Set("Mattermost-User-ID", tc.UserID)
+			}
+			w := httptest.NewRecorder()
+			p.handleAction(w, r)
+			assert.Equal(t, tc.ExpectedCode, w.Code)
+		})
+	}
+}
+
+func TestBuildStartedAttachment(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+
+	siteURL := "https://mattermost.example.com"
+	config := &model.Config{}
+	config.ServiceSettings.SiteURL = &siteURL
+	api.On("GetConfig").Return(config)
+
+	build := &gojenkins.Build{Raw: &gojenkins.BuildResponse{Number: 42, URL: "https://jenkins.example.com/job/jobname/42/"}}
+	attachment := p.buildStartedAttachment("ci:jobname", build)
+
+	assert.Len(t, attachment.Actions, 2)
+	assert.Equal(t, "Abort", attachment.Actions[0].Name)
+	assert.Equal(t, "https://mattermost.example.com/plugins/jenkins/action", attachment.Actions[0].Integration.URL)
+	assert.Equal(t, map[string]interface{}{"action": actionAbort, "job": "ci:jobname", "build": "42"}, attachment.Actions[0].Integration.Context)
+	assert.Equal(t, actionGetLog, attachment.Actions[1].Integration.Context["action"])
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const jenkinsAliasesKey = "_jenkinsAliases_"
+
+var aliasNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Alias is a short name for a job of a channel, with preset build parameters.
+type Alias struct {
+	Name       string
+	CreatorID  string
+	Job        string
+	Parameters map[string]string
+}
+
+// aliasesKey returns the KV store key of the aliases of the channel.
+func aliasesKey(channelID string) string {
+	return jenkinsAliasesKey + channelID
+}
+
+// MergeParameters returns the preset parameters of the alias replaced by the overrides.
+func (a *Alias) MergeParameters(overrides map[string]string) map[string]string {
+	var parameters map[string]string
+	for _, values := range []map[string]string{a.Parameters, overrides} {
+		for name, value := range values {
+			if parameters == nil {
+				parameters = map[string]string{}
+			}
+			parameters[name] = value
+		}
+	}
+	return parameters
+}
+
+// parseAliases decodes the aliases of a channel stored in the KV store, keyed by name.
+func parseAliases(aliasesBytes []byte) (map[string]*Alias, error) {
+	aliases := map[string]*Alias{}
+	if aliasesBytes == nil {
+		return aliases, nil
+	}
+
+	if err := json.Unmarshal(aliasesBytes, &aliases); err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+// getAliases returns the aliases of the channel, keyed by name.
+func (p *Plugin) getAliases(channelID string) (map[string]*Alias, error) {
+	aliasesBytes, appErr := p.API.KVGet(aliasesKey(channelID))
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return parseAliases(aliasesBytes)
+}
+
+// updateAliases changes the aliases of the channel with update and stores them.
+// The aliases are stored only if they weren't changed meanwhile by another server of the cluster,
+// otherwise update is called again with the new aliases. The key is deleted when no alias is left.
+func (p *Plugin) updateAliases(channelID string, update func(aliases map[string]*Alias) error) error {
+	return p.updateKV(aliasesKey(channelID), func(aliasesBytes []byte) ([]byte, error) {
+		aliases, err := parseAliases(aliasesBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := update(aliases); err != nil {
+			return nil, err
+		}
+
+		if len(aliases) == 0 {
+			return nil, nil
+		}
+		return json.Marshal(aliases)
+	})
+}
+
+// addAlias adds an alias to a channel. An existing alias of the channel with the same name is replaced.
+func (p *Plugin) addAlias(channelID string, alias *Alias) error {
+	return p.updateAliases(channelID, func(aliases map[string]*Alias) error {
+		aliases[alias.Name] = alias
+		return nil
+	})
+}
+
+// removeAlias removes an alias from a channel.
+// Returns false if the channel has no alias with this name.
+func (p *Plugin) removeAlias(channelID, name string) (bool, error) {
+	removed := false
+	err := p.updateAliases(channelID, func(aliases map[string]*Alias) error {
+		_, removed = aliases[name]
+		delete(aliases, name)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return removed, nil
+}
+
+// formatAlias formats an alias as an item of a list.
+func formatAlias(alias *Alias) string {
+	msg := fmt.Sprintf("* `%s` - `%s`", alias.Name, alias.Job)
+	if len(alias.Parameters) > 0 {
+		parameters := []string{}
+		for name, value := range alias.Parameters {
+			parameters = append(parameters, name+"="+value)
+		}
+		sort.Strings(parameters)
+		msg += " with " + strings.Join(parameters, ", ")
+	}
+	return msg
+}
+
+func (p *Plugin) executeAliasCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	if len(parameters) == 0 {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to manage aliases.")
+	}
+
+	switch parameters[0] {
+	case "add":
+		return p.executeAliasAddCommand(parameters[1:], instance, args)
+	case "list":
+		if len(parameters) != 1 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to list the aliases.")
+		}
+		return p.executeAliasListCommand(args)
+	case "remove":
+		if len(parameters) != 2 {
+			return p.getCommandResponse(args, "Please specify the name of the alias to remove.")
+		}
+		return p.executeAliasRemoveCommand(parameters[1], args)
+	default:
+		return p.getCommandResponse(args, fmt.Sprintf("Unknown alias command '%s'. Available commands are add, list and remove.", parameters[0]))
+	}
+}
+
+func (p *Plugin) executeAliasAddCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	if len(parameters) < 2 {
+		return p.getCommandResponse(args, "Please specify the name of the alias and the job.")
+	}
+
+	name := parameters[0]
+	if !aliasNameRegexp.MatchString(name) {
+		return p.getCommandResponse(args, fmt.Sprintf("Invalid alias name '%s'. Use letters, digits, '.', '_' and '-' only.", name))
+	}
+
+	jobName, rest, ok := splitJobName(parameters[1:])
+	if !ok || jobName == "" {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to add an alias.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	if _, err := p.getJob(args.UserId, jobName); err != nil {
+		p.API.LogError("Error fetching the job of the alias", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, fmt.Sprintf("Error fetching the job '%s'. Make sure it exists and you are connected to Jenkins.", jobName))
+	}
+
+	alias := &Alias{
+		Name:       name,
+		CreatorID:  args.UserId,
+		Job:        jobName,
+		Parameters: parseKeyValueParameters(rest),
+	}
+	if err := p.addAlias(args.ChannelId, alias); err != nil {
+		p.API.LogError("Error saving the alias", "alias", name, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while saving the alias.")
+	}
+
+	return p.getCommandResponse(args, fmt.Sprintf("Alias added to this channel:\n%s\nUse `/jenkins run %s` to build it.", formatAlias(alias), name))
+}
+
+func (p *Plugin) executeAliasListCommand(args *model.CommandArgs) *model.CommandResponse {
+	aliases, err := p.getAliases(args.ChannelId)
+	if err != nil {
+		p.API.LogError("Error fetching the aliases", "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while fetching the aliases.")
+	}
+
+	if len(aliases) == 0 {
+		return p.getCommandResponse(args, "This channel has no aliases.")
+	}
+
+	names := []string{}
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msg := "###### Aliases of this channel\n"
+	for _, name := range names {
+		msg += formatAlias(aliases[name]) + "\n"
+	}
+
+	return p.getCommandResponse(args, msg)
+}
+
+func (p *Plugin) executeAliasRemoveCommand(name string, args *model.CommandArgs) *model.CommandResponse {
+	removed, err := p.removeAlias(args.ChannelId, name)
+	if err != nil {
+		p.API.LogError("Error removing the alias", "alias", name, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while removing the alias.")
+	}
+
+	if !removed {
+		return p.getCommandResponse(args, fmt.Sprintf("This channel has no alias '%s'.", name))
+	}
+
+	return p.getCommandResponse(args, fmt.Sprintf("The alias '%s' has been removed.", name))
+}
+
+func (p *Plugin) executeRunCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	if len(parameters) == 0 {
+		return p.getCommandResponse(args, "Please specify the alias to run. Use `/jenkins alias list` to find the aliases of this channel.")
+	}
+
+	aliases, err := p.getAliases(args.ChannelId)
+	if err != nil {
+		p.API.LogError("Error fetching the aliases", "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while fetching the aliases.")
+	}
+
+	alias, ok := aliases[parameters[0]]
+	if !ok {
+		return p.getCommandResponse(args, fmt.Sprintf("This channel has no alias '%s'. Use `/jenkins alias list` to find the aliases of this channel.", parameters[0]))
+	}
+
+	// Aliases of jobs of the default instance can be run on another instance with --instance.
+	jobName, ok := qualifyJobSelector(instance, alias.Job)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	return p.startBuild(jobName, alias.MergeParameters(parseKeyValueParameters(parameters[1:])), args)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAliasMergeParameters(t *testing.T) {
+	alias := &Alias{Name: "release", Job: "folder/release", Parameters: map[string]string{"channel": "beta", "dry_run": "true"}}
+	assert.Equal(t, map[string]string{"channel": "stable", "dry_run": "true"}, alias.MergeParameters(map[string]string{"channel": "stable"}))
+	assert.Equal(t, map[string]string{"channel": "beta", "dry_run": "true"}, alias.MergeParameters(nil))
+	assert.Equal(t, map[string]string{"channel": "beta", "dry_run": "true"}, alias.Parameters, "the preset parameters are not modified")
+
+	alias = &Alias{Name: "smoke", Job: "smoke"}
+	assert.Nil(t, alias.MergeParameters(nil))
+}
+
+func TestFormatAlias(t *testing.T) {
+	alias := &Alias{Name: "release", Job: "folder/release"}
+	assert.Equal(t, "* `release` - `folder/release`", formatAlias(alias))
+
+	alias.Parameters = map[string]string{"dry_run": "true", "channel": "beta"}
+	assert.Equal(t, "* `release` - `folder/release` with channel=beta, dry_run=true", formatAlias(alias))
+}
+
+func TestAddAndRemoveAlias(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+
+	var stored []byte
+	api.On("KVGet", "_jenkinsAliases_channel1").Return(func(string) []byte { return stored }, func(string) *model.AppError { return nil })
+	// The first update conflicts with a concurrent change and is attempted again.
+	api.On("KVCompareAndSet", "_jenkinsAliases_channel1", []byte(nil), mock.Anything).Return(false, nil).Once()
+	api.On("KVCompareAndSet", "_jenkinsAliases_channel1", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(2).([]byte)
+	}).Return(true, nil)
+	api.On("KVCompareAndDelete", "_jenkinsAliases_channel1", mock.Anything).Run(func(mock.Arguments) {
+		stored = nil
+	}).Return(true, nil)
+
+	assert.Nil(t, p.addAlias("channel1", &Alias{Name: "release", Job: "folder/release"}))
+	assert.Nil(t, p.addAlias("channel1", &Alias{Name: "release", Job: "folder/release-v2"}))
+
+	var aliases map[string]*Alias
+	assert.Nil(t, json.Unmarshal(stored, &aliases))
+	assert.Len(t, aliases, 1)
+	assert.Equal(t, "folder/release-v2", aliases["release"].Job)
+
+	removed, err := p.removeAlias("channel1", "unknown")
+	assert.Nil(t, err)
+	assert.False(t, removed)
+
+	removed, err = p.removeAlias("channel1", "release")
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	assert.Nil(t, stored)
+}
+
+func TestExecuteAliasAddCommand(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/job/folder/job/release/api/json" {
+			_, _ = res.Write([]byte(`{"name": "release", "url": "http://jenkins/job/folder/job/release/"}`))
+			return
+		}
+		res.WriteHeader(http.StatusNotFound)
+	}))
+	defer testServer.Close()
+
+	p, api := setupTestPlugin(t, testServer.URL)
+	var response string
+	api.On("SendEphemeralPost", "user1", mock.Anything).Run(func(args mock.Arguments) {
+		response = args.Get(1).(*model.Post).Message
+	}).Return(nil)
+	api.On("LogError", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	api.On("KVGet", "_jenkinsAliases_channel1").Return(nil, nil)
+	api.On("KVCompareAndSet", "_jenkinsAliases_channel1", []byte(nil), mock.Anything).Return(true, nil)
+	args := &model.CommandArgs{UserId: "user1", ChannelId: "channel1"}
+
+	p.executeAliasAddCommand([]string{"release", "folder/missing"}, "", args)
+	assert.Equal(t, "Error fetching the job 'folder/missing'. Make sure it exists and you are connected to Jenkins.", response)
+	api.AssertNotCalled(t, "KVCompareAndSet", mock.Anything, mock.Anything, mock.Anything)
+
+	p.executeAliasAddCommand([]string{"release", "folder/release", "dry_run=true"}, "", args)
+	assert.Contains(t, response, "Alias added to this channel:\n* `release` - `folder/release` with dry_run=true")
+	api.AssertCalled(t, "KVCompareAndSet", "_jenkinsAliases_channel1", []byte(nil), mock.Anything)
+}
+
+func TestExecuteRunCommandInstanceMismatch(t *testing.T) {
+	p, api := setupTestPlugin(t, "https://jenkins.example.com")
+	aliasesBytes, err := json.Marshal(map[string]*Alias{"deploy": {Name: "deploy", Job: "release:deploy"}})
+	assert.Nil(t, err)
+	api.On("KVGet", "_jenkinsAliases_channel1").Return(aliasesBytes, nil)
+	var response string
+	api.On("SendEphemeralPost", "user1", mock.Anything).Run(func(args mock.Arguments) {
+		response = args.Get(1).(*model.Post).Message
+	}).Return(nil)
+
+	p.executeRunCommand([]string{"deploy"}, "ci", &model.CommandArgs{UserId: "user1", ChannelId: "channel1"})
+	assert.Equal(t, instanceMismatchResponse("ci", "release:deploy"), response)
+}
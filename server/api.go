@@ -2,7 +2,6 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -18,6 +17,10 @@ func (p *Plugin) InitAPI() *mux.Router {
 	r := mux.NewRouter()
 	r.HandleFunc("/triggerBuild", p.handleBuildTrigger).Methods("POST")
 	r.HandleFunc("/createJob", p.handleJobCreation).Methods("POST")
+	r.HandleFunc("/webhook", p.handleWebhook).Methods("POST")
+	r.HandleFunc("/action", p.handleAction).Methods("POST")
+	r.HandleFunc("/input", p.handleInputSubmission).Methods("POST")
+	r.HandleFunc("/autocomplete/jobs", p.handleJobsAutocomplete).Methods("GET")
 	r.HandleFunc("/assets/jenkins.png", p.handleProfileImage).Methods("GET")
 	return r
 }
@@ -48,22 +51,28 @@ func (p *Plugin) handleBuildTrigger(w http.ResponseWriter, r *http.Request) {
 
 	var request model.SubmitDialogRequest
 	err := json.Unmarshal(body, &request)
-	if err == nil {
+	if err != nil {
 		p.API.LogError("failed to decode request")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	parameters := make(map[string]string)
-	for k, v := range request.Submission {
-		parameters[k] = v.(string)
-	}
-
-	build, err := p.triggerJenkinsJob(userID, request.ChannelId, jobName, parameters)
-	if err != nil {
-		p.API.LogError("Error triggering build", "job_name", jobName, "err", err.Error())
+	if allowed, denial := p.checkCommandPermission(userID, request.ChannelId, "build"); !allowed {
+		writeDialogError(w, denial)
 		return
 	}
-	p.createPost(userID, request.ChannelId, fmt.Sprintf("Job '%s' - #%d has been started\nBuild URL : %s", decodedJobName, build.GetBuildNumber(), build.GetUrl()))
+
+	parameters := dialogSubmissionToParameters(request.Submission)
+
+	// The build is followed in the background so that the dialog is closed right away.
+	go func() {
+		build, err := p.triggerJenkinsJob(userID, request.ChannelId, jobName, parameters)
+		if err != nil {
+			p.API.LogError("Error triggering build", "job_name", jobName, "err", err.Error())
+			return
+		}
+		p.followBuild(userID, request.ChannelId, decodedJobName, build)
+	}()
 }
 
 func (p *Plugin) handleJobCreation(w http.ResponseWriter, r *http.Request) {
@@ -77,14 +86,22 @@ func (p *Plugin) handleJobCreation(w http.ResponseWriter, r *http.Request) {
 
 	var request model.SubmitDialogRequest
 	err := json.Unmarshal(body, &request)
-	if err == nil {
+	if err != nil {
 		p.API.LogError("failed to decode request")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if allowed, denial := p.checkCommandPermission(userID, request.ChannelId, "createjob"); !allowed {
+		writeDialogError(w, denial)
 		return
 	}
 
 	jobInputs := make(map[string]string)
 	for k, v := range request.Submission {
-		jobInputs[k] = v.(string)
+		if value, ok := v.(string); ok {
+			jobInputs[k] = value
+		}
 	}
 	if err := p.sendJobCreateRequest(userID, request.ChannelId, jobInputs); err != nil {
 		p.API.LogWarn("Error sending job creation request", "err", err)
@@ -112,3 +129,9 @@ func (p *Plugin) handleProfileImage(w http.ResponseWriter, r *http.Request) {
 		p.API.LogError("can't copy image profile to http response writer", "err", err.Error())
 	}
 }
+
+// writeDialogError answers an interactive dialog submission with an error displayed in the dialog.
+func writeDialogError(w http.ResponseWriter, message string) {
+	b, _ := json.Marshal(model.SubmitDialogResponse{Error: message})
+	_, _ = w.Write(b)
+}
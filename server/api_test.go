@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleJobCreation(t *testing.T) {
+	p := &Plugin{}
+
+	for name, tc := range map[string]struct {
+		UserID       string
+		Body         string
+		ExpectedCode int
+	}{
+		"missing user": {
+			Body:         `{"channel_id": "channel1", "submission": {"JobName": "jobname"}}`,
+			ExpectedCode: http.StatusUnauthorized,
+		},
+		"invalid body": {
+			UserID:       "user1",
+			Body:         `{`,
+			ExpectedCode: http.StatusBadRequest,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("LogError", "failed to decode request").Return()
+			p.SetAPI(api)
+
+			r := httptest.NewRequest(http.MethodPost, "/createJob", strings.NewReader(tc.Body))
+			if tc.UserID != "" {
+				r.Header.Set("Mattermost-User-ID", tc.UserID)
+			}
+			w := httptest.NewRecorder()
+			p.handleJobCreation(w, r)
+			assert.Equal(t, tc.ExpectedCode, w.Code)
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	defaultMaxArtifactFileSizeMB     = 25
+	defaultMaxArtifactsRequestSizeMB = 100
+
+	megabyte = 1024 * 1024
+)
+
+// artifactOptions selects which artifacts of a build are uploaded and how.
+type artifactOptions struct {
+	// Match is a glob pattern the artifacts must match, either on their relative path or on their file name.
+	Match string
+
+	// Zip bundles the artifacts into a single zip file.
+	Zip bool
+}
+
+// buildArtifact is an artifact of a build, along with its size and the reason it is skipped, if any.
+type buildArtifact struct {
+	Artifact     gojenkins.Artifact
+	RelativePath string
+	URL          string
+
+	// Size is -1 when Jenkins doesn't report the size of the artifact.
+	Size       int64
+	SkipReason string
+}
+
+// matchArtifact checks if the artifact matches the glob pattern, either on its relative path or on its file name.
+func matchArtifact(pattern, relativePath string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	if matched, _ := path.Match(pattern, relativePath); matched {
+		return true
+	}
+	matched, _ := path.Match(pattern, path.Base(relativePath))
+	return matched
+}
+
+// selectArtifacts splits the artifacts between the ones to upload and the ones which are skipped
+// because they exceed the maximum size of a file or of the whole request.
+// Artifacts of unknown size are selected, their size is checked while they are downloaded.
+func selectArtifacts(artifacts []buildArtifact, maxFileSize, maxRequestSize int64) ([]buildArtifact, []buildArtifact) {
+	selected := []buildArtifact{}
+	skipped := []buildArtifact{}
+
+	var total int64
+	for _, artifact := range artifacts {
+		switch {
+		case artifact.Size > maxFileSize:
+			artifact.SkipReason = fmt.Sprintf("larger than %s", formatSize(maxFileSize))
+			skipped = append(skipped, artifact)
+		case artifact.Size > 0 && total+artifact.Size > maxRequestSize:
+			artifact.SkipReason = fmt.Sprintf("exceeds the limit of %s per request", formatSize(maxRequestSize))
+			skipped = append(skipped, artifact)
+		default:
+			if artifact.Size > 0 {
+				total += artifact.Size
+			}
+			selected = append(selected, artifact)
+		}
+	}
+
+	return selected, skipped
+}
+
+// formatSize formats a size in bytes as megabytes.
+func formatSize(size int64) string {
+	return fmt.Sprintf("%.1f MB", float64(size)/megabyte)
+}
+
+// newArtifactRequest creates a request to an artifact, authenticated as the Jenkins user.
+func newArtifactRequest(jenkins *gojenkins.Jenkins, method, artifactPath string) (*http.Request, error) {
+	req, err := http.NewRequest(method, jenkins.Server+artifactPath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if jenkins.Requester.BasicAuth != nil {
+		req.SetBasicAuth(jenkins.Requester.BasicAuth.Username, jenkins.Requester.BasicAuth.Password)
+	}
+	return req, nil
+}
+
+// getArtifactSize returns the size of an artifact from the headers of a HEAD request, without downloading it.
+// Returns -1 if the size is unknown.
+func getArtifactSize(jenkins *gojenkins.Jenkins, artifactPath string) (int64, error) {
+	req, err := newArtifactRequest(jenkins, http.MethodHead, artifactPath)
+	if err != nil {
+		return 0, err
+	}
+
+	response, err := jenkins.Requester.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return 0, errors.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	return response.ContentLength, nil
+}
+
+// downloadArtifact downloads an artifact, reading limit bytes at most so that an artifact larger than expected,
+// like one of unknown size, isn't loaded whole into memory.
+// The last boolean return value is false if the artifact is larger than limit.
+func downloadArtifact(jenkins *gojenkins.Jenkins, artifactPath string, limit int64) ([]byte, bool, error) {
+	req, err := newArtifactRequest(jenkins, http.MethodGet, artifactPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	response, err := jenkins.Requester.Client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, false, errors.Errorf("unexpected status code %d", response.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > limit {
+		return nil, false, nil
+	}
+	return data, true, nil
+}
+
+// artifactDownloadLimit returns how many bytes of the next artifact can be downloaded, given the limit of the file
+// and the bytes already downloaded for the request, along with the reason to skip the artifact if it is larger.
+func artifactDownloadLimit(fileLimit int64, fileLimitReason string, maxRequestSize, downloaded int64) (int64, string) {
+	if remaining := maxRequestSize - downloaded; remaining < fileLimit {
+		return remaining, fmt.Sprintf("exceeds the limit of %s per request", formatSize(maxRequestSize))
+	}
+	return fileLimit, fileLimitReason
+}
+
+// getArtifactLimits returns the maximum size of an artifact and of all the artifacts uploaded at once.
+// The maximum size of an artifact is capped by the maximum size of files accepted by the Mattermost server.
+func (p *Plugin) getArtifactLimits() (int64, int64) {
+	config := p.getConfiguration()
+
+	maxFileSize := int64(defaultMaxArtifactFileSizeMB) * megabyte
+	if config.MaxArtifactFileSize > 0 {
+		maxFileSize = int64(config.MaxArtifactFileSize) * megabyte
+	}
+
+	maxRequestSize := int64(defaultMaxArtifactsRequestSizeMB) * megabyte
+	if config.MaxArtifactsRequestSize > 0 {
+		maxRequestSize = int64(config.MaxArtifactsRequestSize) * megabyte
+	}
+
+	if serverMaxFileSize := p.API.GetConfig().FileSettings.MaxFileSize; serverMaxFileSize != nil && *serverMaxFileSize < maxFileSize {
+		maxFileSize = *serverMaxFileSize
+	}
+
+	return maxFileSize, maxRequestSize
+}
+
+// fetchAndUploadArtifactsOfABuild checks if the specified job and build has artifacts and
+// uploads the ones matching the options to MM server if artifacts are present.
+// Artifacts exceeding the size limits are listed with their links instead.
+// If build number is not specified, the method checks the last build of the job for artifacts.
+func (p *Plugin) fetchAndUploadArtifactsOfABuild(userID, channelID, jobName, buildID string, options artifactOptions) error {
+	build, buildErr := p.getBuild(jobName, userID, buildID)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	instance := jobInstance(jobName)
+	artifacts := []buildArtifact{}
+	for i, a := range build.GetArtifacts() {
+		relativePath := build.Raw.Artifacts[i].RelativePath
+		if !matchArtifact(options.Match, relativePath) {
+			continue
+		}
+
+		size, err := getArtifactSize(build.Jenkins, a.Path)
+		if err != nil {
+			p.API.LogWarn("Error fetching the size of the artifact", "artifact", relativePath, "err", err.Error())
+			size = -1
+		}
+
+		artifacts = append(artifacts, buildArtifact{
+			Artifact:     a,
+			RelativePath: relativePath,
+			URL:          build.GetUrl() + "artifact/" + relativePath,
+			Size:         size,
+		})
+	}
+
+	if len(artifacts) == 0 {
+		msg := fmt.Sprintf("No artifacts found in the build #%d of the job '%s'", build.GetBuildNumber(), jobName)
+		if options.Match != "" {
+			msg += fmt.Sprintf(" matching '%s'", options.Match)
+		}
+		p.createPost(userID, channelID, instance, msg)
+		return nil
+	}
+	p.createPost(userID, channelID, instance, fmt.Sprintf("%d Artifact(s) found in the build #%d of the job '%s'", len(artifacts), build.GetBuildNumber(), jobName))
+
+	maxFileSize, maxRequestSize := p.getArtifactLimits()
+	selected, skipped := selectArtifacts(artifacts, maxFileSize, maxRequestSize)
+
+	var uploadErr error
+	if options.Zip {
+		skipped, uploadErr = p.uploadZippedArtifacts(userID, channelID, jobName, build.GetBuildNumber(), selected, skipped, maxFileSize, maxRequestSize)
+	} else {
+		skipped, uploadErr = p.uploadArtifacts(userID, channelID, instance, selected, skipped, maxFileSize, maxRequestSize)
+	}
+
+	if len(skipped) > 0 {
+		msg := fmt.Sprintf("%d artifact(s) have not been uploaded, download them from Jenkins:", len(skipped))
+		for _, artifact := range skipped {
+			msg += fmt.Sprintf("\n* [%s](%s) - %s", artifact.RelativePath, artifact.URL, artifact.SkipReason)
+		}
+		p.createPost(userID, channelID, instance, msg)
+	}
+
+	return uploadErr
+}
+
+// uploadArtifacts uploads each artifact as a separate file.
+// Artifacts of unknown size which turn out to exceed maxFileSize, or to make the downloaded bytes exceed
+// maxRequestSize, are added to the skipped ones.
+func (p *Plugin) uploadArtifacts(userID, channelID, instance string, artifacts, skipped []buildArtifact, maxFileSize, maxRequestSize int64) ([]buildArtifact, error) {
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	var downloaded int64
+	for _, artifact := range artifacts {
+		limit, reason := artifactDownloadLimit(maxFileSize, fmt.Sprintf("larger than %s", formatSize(maxFileSize)), maxRequestSize, downloaded)
+		fileData, ok, fileDataErr := downloadArtifact(artifact.Artifact.Jenkins, artifact.Artifact.Path, limit)
+		if fileDataErr != nil {
+			return skipped, errors.Wrap(fileDataErr, "Error fetching file data")
+		}
+
+		if !ok {
+			artifact.SkipReason = reason
+			skipped = append(skipped, artifact)
+			continue
+		}
+		downloaded += int64(len(fileData))
+
+		p.createEphemeralPost(userID, channelID, fmt.Sprintf("Uploading artifact '%s' ...", artifact.Artifact.FileName))
+		fileInfo, fileInfoErr := p.API.UploadFile(fileData, channelID, artifact.Artifact.FileName)
+		if fileInfoErr != nil {
+			return skipped, errors.Wrap(fileInfoErr, "Error uploading file")
+		}
+		p.createPost(userID, channelID, instance, fmt.Sprintf("Artifact '%s' : %s", fileInfo.Name, siteURL+"/api/v4/files/"+fileInfo.Id))
+	}
+
+	return skipped, nil
+}
+
+// uploadZippedArtifacts bundles the artifacts into a single zip file and uploads it.
+// Artifacts which would make the zip file exceed maxFileSize, or the downloaded bytes exceed maxRequestSize,
+// are added to the skipped ones. All the artifacts are skipped if the finished zip file still exceeds maxFileSize.
+func (p *Plugin) uploadZippedArtifacts(userID, channelID, jobName string, buildNumber int64, artifacts, skipped []buildArtifact, maxFileSize, maxRequestSize int64) ([]buildArtifact, error) {
+	if len(artifacts) == 0 {
+		return skipped, nil
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := zip.NewWriter(buffer)
+	zipped := []buildArtifact{}
+	var downloaded int64
+	for _, artifact := range artifacts {
+		// Artifacts are compressed, so their size is a good upper bound of the size they add to the zip file.
+		fileLimit := maxFileSize - int64(buffer.Len())
+		limit, reason := artifactDownloadLimit(fileLimit, fmt.Sprintf("the zip file would be larger than %s", formatSize(maxFileSize)), maxRequestSize, downloaded)
+		fileData, ok, fileDataErr := downloadArtifact(artifact.Artifact.Jenkins, artifact.Artifact.Path, limit)
+		if fileDataErr != nil {
+			return skipped, errors.Wrap(fileDataErr, "Error fetching file data")
+		}
+
+		if !ok {
+			artifact.SkipReason = reason
+			skipped = append(skipped, artifact)
+			continue
+		}
+		downloaded += int64(len(fileData))
+
+		file, err := writer.Create(artifact.RelativePath)
+		if err != nil {
+			return skipped, errors.Wrap(err, "Error adding the artifact to the zip file")
+		}
+		if _, err := file.Write(fileData); err != nil {
+			return skipped, errors.Wrap(err, "Error adding the artifact to the zip file")
+		}
+		zipped = append(zipped, artifact)
+	}
+
+	if err := writer.Close(); err != nil {
+		return skipped, errors.Wrap(err, "Error creating the zip file")
+	}
+
+	if len(zipped) == 0 {
+		return skipped, nil
+	}
+
+	// The headers and the central directory of the zip file come on top of the compressed artifacts.
+	if int64(buffer.Len()) > maxFileSize {
+		for _, artifact := range zipped {
+			artifact.SkipReason = fmt.Sprintf("the zip file would be larger than %s", formatSize(maxFileSize))
+			skipped = append(skipped, artifact)
+		}
+		return skipped, nil
+	}
+
+	zippedPaths := []string{}
+	for _, artifact := range zipped {
+		zippedPaths = append(zippedPaths, artifact.RelativePath)
+	}
+
+	_, jobPath := parseJobSelector(jobName)
+	filename := fmt.Sprintf("%s-%d-artifacts.zip", strings.ReplaceAll(jobPath, "/", "-"), buildNumber)
+	p.createEphemeralPost(userID, channelID, fmt.Sprintf("Uploading %d artifact(s) as '%s' ...", len(zippedPaths), filename))
+	fileInfo, fileInfoErr := p.API.UploadFile(buffer.Bytes(), channelID, filename)
+	if fileInfoErr != nil {
+		return skipped, errors.Wrap(fileInfoErr, "Error uploading file")
+	}
+
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	p.createPost(userID, channelID, jobInstance(jobName), fmt.Sprintf("Artifacts '%s' : %s\n* %s", fileInfo.Name, siteURL+"/api/v4/files/"+fileInfo.Id, strings.Join(zippedPaths, "\n* ")))
+	return skipped, nil
+}
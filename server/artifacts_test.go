@@ -0,0 +1,175 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/waseem18/gojenkins"
+)
+
+func TestMatchArtifact(t *testing.T) {
+	assert.True(t, matchArtifact("", "app/build/app.apk"))
+	assert.True(t, matchArtifact("*.apk", "app/build/app.apk"))
+	assert.True(t, matchArtifact("app/*/*.apk", "app/build/app.apk"))
+	assert.False(t, matchArtifact("*.ipa", "app/build/app.apk"))
+}
+
+func TestSelectArtifacts(t *testing.T) {
+	artifacts := []buildArtifact{
+		{RelativePath: "small.txt", Size: 1 * megabyte},
+		{RelativePath: "huge.bin", Size: 30 * megabyte},
+		{RelativePath: "medium.zip", Size: 8 * megabyte},
+		{RelativePath: "unknown.log", Size: -1},
+		{RelativePath: "another.zip", Size: 8 * megabyte},
+	}
+
+	selected, skipped := selectArtifacts(artifacts, 10*megabyte, 12*megabyte)
+
+	selectedPaths := []string{}
+	for _, a := range selected {
+		selectedPaths = append(selectedPaths, a.RelativePath)
+	}
+	assert.Equal(t, []string{"small.txt", "medium.zip", "unknown.log"}, selectedPaths)
+
+	assert.Len(t, skipped, 2)
+	assert.Equal(t, "huge.bin", skipped[0].RelativePath)
+	assert.Equal(t, "larger than 10.0 MB", skipped[0].SkipReason)
+	assert.Equal(t, "another.zip", skipped[1].RelativePath)
+	assert.Equal(t, "exceeds the limit of 12.0 MB per request", skipped[1].SkipReason)
+}
+
+func TestGetArtifactSize(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodHead && req.URL.Path == "/job/jobname/42/artifact/app.apk" {
+			username, password, ok := req.BasicAuth()
+			if !ok || username != "username1" || password == "" {
+				res.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			res.Header().Set("Content-Length", "2048")
+			return
+		}
+		if req.URL.Path == "/api/json" {
+			res.WriteHeader(http.StatusOK)
+			return
+		}
+		res.WriteHeader(http.StatusNotFound)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	size, err := getArtifactSize(jenkins, "/job/jobname/42/artifact/app.apk")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2048), size)
+
+	_, err = getArtifactSize(jenkins, "/job/jobname/42/artifact/missing.apk")
+	assert.NotNil(t, err)
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/job/jobname/42/artifact/app.log" {
+			_, _ = res.Write([]byte(strings.Repeat("x", 100)))
+			return
+		}
+		res.WriteHeader(http.StatusNotFound)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	data, ok, err := downloadArtifact(jenkins, "/job/jobname/42/artifact/app.log", 100)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Len(t, data, 100)
+
+	_, ok, err = downloadArtifact(jenkins, "/job/jobname/42/artifact/app.log", 99)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	_, _, err = downloadArtifact(jenkins, "/job/jobname/42/artifact/missing.log", 100)
+	assert.NotNil(t, err)
+}
+
+func TestUploadArtifactsOfUnknownSize(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/job/jobname/42/artifact/first.log", "/job/jobname/42/artifact/second.log":
+			_, _ = res.Write([]byte(strings.Repeat("x", 60)))
+		case "/job/jobname/42/artifact/huge.log":
+			_, _ = res.Write([]byte(strings.Repeat("x", 200)))
+		default:
+			res.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	p, api := setupTestPlugin(t, testServer.URL)
+	siteURL := "https://mattermost.example.com"
+	config := &model.Config{}
+	config.ServiceSettings.SiteURL = &siteURL
+	api.On("GetConfig").Return(config)
+	api.On("SendEphemeralPost", "user1", mock.Anything).Return(nil)
+	api.On("UploadFile", mock.Anything, "channel1", mock.Anything).Return(&model.FileInfo{Id: "file1", Name: "first.log"}, nil)
+	api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil)
+
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	artifacts := []buildArtifact{}
+	for _, name := range []string{"huge.log", "first.log", "second.log"} {
+		artifacts = append(artifacts, buildArtifact{
+			Artifact:     gojenkins.Artifact{Jenkins: jenkins, FileName: name, Path: "/job/jobname/42/artifact/" + name},
+			RelativePath: name,
+			Size:         -1,
+		})
+	}
+
+	skipped, err := p.uploadArtifacts("user1", "channel1", "", artifacts, nil, 100, 100)
+	assert.Nil(t, err)
+	assert.Len(t, skipped, 2)
+	assert.Equal(t, "huge.log", skipped[0].RelativePath)
+	assert.Equal(t, "larger than 0.0 MB", skipped[0].SkipReason)
+	assert.Equal(t, "second.log", skipped[1].RelativePath)
+	assert.Equal(t, "exceeds the limit of 0.0 MB per request", skipped[1].SkipReason)
+	api.AssertNumberOfCalls(t, "UploadFile", 1)
+}
+
+func TestUploadZippedArtifactsLargerThanLimit(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/job/jobname/42/artifact/report.txt" {
+			_, _ = res.Write([]byte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWX"))
+			return
+		}
+		res.WriteHeader(http.StatusNotFound)
+	}))
+	defer testServer.Close()
+
+	p, api := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	artifacts := []buildArtifact{{
+		Artifact:     gojenkins.Artifact{Jenkins: jenkins, FileName: "report.txt", Path: "/job/jobname/42/artifact/report.txt"},
+		RelativePath: "report.txt",
+		Size:         60,
+	}}
+
+	// The artifact fits, but not with the headers and the central directory of the zip file.
+	skipped, err := p.uploadZippedArtifacts("user1", "channel1", "jobname", 42, artifacts, nil, 100, 1000)
+	assert.Nil(t, err)
+	assert.Len(t, skipped, 1)
+	assert.Equal(t, "report.txt", skipped[0].RelativePath)
+	assert.Equal(t, "the zip file would be larger than 0.0 MB", skipped[0].SkipReason)
+	api.AssertNotCalled(t, "UploadFile", mock.Anything, mock.Anything, mock.Anything)
+}
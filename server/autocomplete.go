@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// jobsCacheTTL is how long the jobs of a Jenkins instance are cached for autocompletion.
+	jobsCacheTTL = time.Minute
+
+	maxAutocompleteItems = 25
+)
+
+type jobsCacheEntry struct {
+	jobs      []jobListEntry
+	expiresAt time.Time
+}
+
+// jobsFetch is a fetch of the jobs of a Jenkins instance in progress. done is closed once it completes.
+type jobsFetch struct {
+	done chan struct{}
+	jobs []jobListEntry
+	err  error
+}
+
+// getCachedJobs returns all the jobs of the folder of a Jenkins instance visible to the user.
+// The whole instance is listed if the folder is empty.
+// The jobs are cached for a short time so that autocompletion doesn't query Jenkins on every keystroke,
+// and concurrent requests for the same jobs wait for a single fetch. Errors are not cached.
+func (p *Plugin) getCachedJobs(userID, instance, folder string) ([]jobListEntry, error) {
+	folder = strings.Trim(folder, "/")
+	key := jenkinsUserInfoKey(userID, instance) + "/" + folder
+
+	p.jobsCacheLock.Lock()
+	if entry, ok := p.jobsCache[key]; ok && time.Now().Before(entry.expiresAt) {
+		p.jobsCacheLock.Unlock()
+		return entry.jobs, nil
+	}
+	if fetch, ok := p.jobsFetches[key]; ok {
+		p.jobsCacheLock.Unlock()
+		<-fetch.done
+		return fetch.jobs, fetch.err
+	}
+	if p.jobsFetches == nil {
+		p.jobsFetches = map[string]*jobsFetch{}
+	}
+	fetch := &jobsFetch{done: make(chan struct{})}
+	p.jobsFetches[key] = fetch
+	p.jobsCacheLock.Unlock()
+
+	fetch.jobs, fetch.err = p.listJobs(userID, instance, folder)
+
+	p.jobsCacheLock.Lock()
+	defer p.jobsCacheLock.Unlock()
+	delete(p.jobsFetches, key)
+	close(fetch.done)
+	if fetch.err != nil {
+		return nil, fetch.err
+	}
+
+	// Expired entries are evicted, so that the cache doesn't grow with every user who ever autocompleted a job.
+	now := time.Now()
+	for cachedKey, entry := range p.jobsCache {
+		if !now.Before(entry.expiresAt) {
+			delete(p.jobsCache, cachedKey)
+		}
+	}
+	if p.jobsCache == nil {
+		p.jobsCache = map[string]jobsCacheEntry{}
+	}
+	p.jobsCache[key] = jobsCacheEntry{jobs: fetch.jobs, expiresAt: now.Add(jobsCacheTTL)}
+
+	return fetch.jobs, nil
+}
+
+// handleJobsAutocomplete returns the jobs matching the job name being typed in a slash command.
+func (p *Plugin) handleJobsAutocomplete(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	userInput := r.URL.Query().Get("user_input")
+	parsed := r.URL.Query().Get("parsed")
+	typed := strings.TrimPrefix(strings.TrimSpace(strings.TrimPrefix(userInput, parsed)), "\"")
+
+	_, instance, _ := extractFlag(strings.Fields(userInput), "instance")
+	selector, ok := qualifyJobSelector(instance, typed)
+	instance, prefix := parseJobSelector(selector)
+
+	items := []model.AutocompleteListItem{}
+	if _, err := p.getConfiguration().getInstanceURL(instance); ok && err == nil {
+		jobs, err := p.getCachedJobs(userID, instance, "")
+		if err != nil {
+			// Users who are not connected to Jenkins simply get no suggestions.
+			p.API.LogDebug("Error fetching jobs for autocompletion", "instance", instance, "err", err.Error())
+		}
+
+		for _, job := range jobs {
+			if len(items) >= maxAutocompleteItems {
+				break
+			}
+			if !strings.HasPrefix(job.Name, prefix) {
+				continue
+			}
+
+			item := qualifyJobName(instance, job.Name)
+			if strings.Contains(item, " ") {
+				item = "\"" + item + "\""
+			}
+			items = append(items, model.AutocompleteListItem{
+				Item:     item,
+				HelpText: jobColorStatus(job.Color),
+			})
+		}
+	}
+
+	b, _ := json.Marshal(items)
+	_, _ = w.Write(b)
+}
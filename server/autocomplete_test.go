@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleJobsAutocomplete(t *testing.T) {
+	jobRequests := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/api/json" && req.URL.Query().Get("tree") != "" {
+			jobRequests++
+			_, _ = res.Write([]byte(`{"jobs":[
+				{"_class": "hudson.model.FreeStyleProject", "name": "jobname", "url": "http://jenkins/job/jobname/", "color": "blue"},
+				{"_class": "hudson.model.FreeStyleProject", "name": "job with space", "url": "http://jenkins/job/job%20with%20space/", "color": "red"},
+				{"_class": "hudson.model.FreeStyleProject", "name": "other", "url": "http://jenkins/job/other/", "color": "blue"}
+			]}`))
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+
+	autocomplete := func(userID, userInput, parsed string) (int, []model.AutocompleteListItem) {
+		query := url.Values{}
+		query.Set("user_input", userInput)
+		query.Set("parsed", parsed)
+		r := httptest.NewRequest(http.MethodGet, "/autocomplete/jobs?"+query.Encode(), nil)
+		if userID != "" {
+			r.Header.Set("Mattermost-User-ID", userID)
+		}
+		w := httptest.NewRecorder()
+		p.handleJobsAutocomplete(w, r)
+
+		items := []model.AutocompleteListItem{}
+		if w.Code == http.StatusOK {
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &items))
+		}
+		return w.Code, items
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		code, _ := autocomplete("", "/jenkins build job", "/jenkins build ")
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("matches the typed prefix", func(t *testing.T) {
+		code, items := autocomplete("user1", "/jenkins build job", "/jenkins build ")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []model.AutocompleteListItem{
+			{Item: "jobname", HelpText: "Success"},
+			{Item: "\"job with space\"", HelpText: "Failed"},
+		}, items)
+	})
+
+	t.Run("uses the cached jobs", func(t *testing.T) {
+		code, items := autocomplete("user1", "/jenkins build ot", "/jenkins build ")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []model.AutocompleteListItem{{Item: "other", HelpText: "Success"}}, items)
+		assert.Equal(t, 1, jobRequests)
+	})
+
+	t.Run("unknown instance", func(t *testing.T) {
+		code, items := autocomplete("user1", "/jenkins build unknown:job", "/jenkins build ")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, items)
+	})
+}
+
+func TestGetCachedJobs(t *testing.T) {
+	var jobRequests int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/api/json" && req.URL.Query().Get("tree") != "" {
+			if atomic.AddInt32(&jobRequests, 1) == 1 {
+				close(started)
+			}
+			<-release
+			_, _ = res.Write([]byte(`{"jobs":[{"_class": "hudson.model.FreeStyleProject", "name": "jobname", "url": "http://jenkins/job/jobname/", "color": "blue"}]}`))
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	p.jobsCache = map[string]jobsCacheEntry{
+		"expired": {expiresAt: time.Now().Add(-time.Second)},
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]jobListEntry, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			jobs, err := p.getCachedJobs("user1", "", "")
+			assert.Nil(t, err)
+			results[i] = jobs
+		}(i)
+		if i == 0 {
+			<-started
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&jobRequests))
+	for _, jobs := range results {
+		assert.Len(t, jobs, 1)
+		assert.Equal(t, "jobname", jobs[0].Name)
+	}
+	assert.NotContains(t, p.jobsCache, "expired")
+	assert.Len(t, p.jobsCache, 1)
+	assert.Empty(t, p.jobsFetches)
+}
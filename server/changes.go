@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// maxChangesBuilds limits the number of builds the changes are aggregated across.
+	maxChangesBuilds = 50
+
+	// maxChangesScannedBuilds limits how far back in the builds of a job the changes are looked for.
+	maxChangesScannedBuilds = 500
+
+	// maxListedChanges limits the number of commits listed in a change summary.
+	maxListedChanges = 30
+
+	shortCommitIDLength = 8
+)
+
+// buildChange is a commit built for the first time by a build.
+type buildChange struct {
+	Build    int64
+	CommitID string
+	Author   string
+	Message  string
+}
+
+type changeSet struct {
+	Items []struct {
+		CommitID string `json:"commitId"`
+		Msg      string `json:"msg"`
+		Comment  string `json:"comment"`
+		Author   struct {
+			FullName string `json:"fullName"`
+		} `json:"author"`
+	} `json:"items"`
+}
+
+// changesItemsTree is the tree query selecting the commits of a change set.
+const changesItemsTree = "items[commitId,msg,comment,author[fullName]]"
+
+// changesResponse holds the commits of a build.
+// Freestyle builds expose their commits in changeSet, Pipeline builds in changeSets, one per repository.
+type changesResponse struct {
+	Number     int64       `json:"number"`
+	ChangeSet  changeSet   `json:"changeSet"`
+	ChangeSets []changeSet `json:"changeSets"`
+}
+
+// Changes returns the commits of the build.
+// Recent versions of Jenkins expose the commits of freestyle builds in both fields, so changeSet is only
+// used when changeSets is empty.
+func (r *changesResponse) Changes() []buildChange {
+	sets := r.ChangeSets
+	if len(sets) == 0 {
+		sets = []changeSet{r.ChangeSet}
+	}
+
+	changes := []buildChange{}
+	for _, set := range sets {
+		for _, item := range set.Items {
+			message := item.Msg
+			if message == "" {
+				message = item.Comment
+			}
+			changes = append(changes, buildChange{
+				Build:    r.Number,
+				CommitID: item.CommitID,
+				Author:   item.Author.FullName,
+				Message:  firstLine(message),
+			})
+		}
+	}
+	return changes
+}
+
+// getBuildChanges fetches the commits of the build with the given API path.
+// Returns nil if the build doesn't exist.
+func getBuildChanges(jenkins *gojenkins.Jenkins, buildBase string) ([]buildChange, error) {
+	response := changesResponse{}
+	query := map[string]string{"tree": fmt.Sprintf("number,changeSet[%s],changeSets[%s]", changesItemsTree, changesItemsTree)}
+	resp, err := jenkins.Requester.GetJSON(buildBase, &response, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the changes of the build")
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching the changes of the build", resp.StatusCode)
+	}
+
+	return response.Changes(), nil
+}
+
+// getBuildsChanges fetches the commits of the builds of the job after the build since, up to the build upTo,
+// from the newest build to the oldest, with a single request.
+// count is the number of the last builds of the job the builds are looked for in.
+func getBuildsChanges(job *gojenkins.Job, since, upTo, count int64) ([]buildChange, error) {
+	// builds is limited to the last 100 builds by Jenkins, allBuilds isn't.
+	response := struct {
+		Builds []changesResponse `json:"allBuilds"`
+	}{}
+	query := map[string]string{
+		"tree": fmt.Sprintf("allBuilds[number,changeSet[%s],changeSets[%s]]{0,%d}", changesItemsTree, changesItemsTree, count),
+	}
+	resp, err := job.Jenkins.Requester.GetJSON(job.Base, &response, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the changes of the builds")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching the changes of the builds", resp.StatusCode)
+	}
+
+	changes := []buildChange{}
+	for _, build := range response.Builds {
+		if build.Number > since && build.Number <= upTo {
+			changes = append(changes, build.Changes()...)
+		}
+	}
+	return changes, nil
+}
+
+// formatBuildChanges renders a list of commits, limited to maxListedChanges.
+func formatBuildChanges(changes []buildChange, withBuild bool) string {
+	msg := ""
+	for i, change := range changes {
+		if i == maxListedChanges {
+			msg += fmt.Sprintf("\n* and %d more", len(changes)-maxListedChanges)
+			break
+		}
+
+		commitID := change.CommitID
+		if len(commitID) > shortCommitIDLength {
+			commitID = commitID[:shortCommitIDLength]
+		}
+		msg += fmt.Sprintf("\n* `%s` %s - %s", commitID, change.Message, change.Author)
+		if withBuild {
+			msg += fmt.Sprintf(" (#%d)", change.Build)
+		}
+	}
+	return msg
+}
+
+// postBuildChanges posts the commits of a build. If since is not 0, the commits of all the builds
+// after the build since, up to the given build, are posted.
+// The last build of the job is used if buildID is an empty string.
+func (p *Plugin) postBuildChanges(userID, channelID, jobName, buildID string, since int64) error {
+	instance := jobInstance(jobName)
+	if since == 0 {
+		build, err := p.getBuild(jobName, userID, buildID)
+		if err != nil {
+			return err
+		}
+
+		buildNumber := build.GetBuildNumber()
+		changes, err := getBuildChanges(build.Jenkins, build.Base)
+		if err != nil {
+			return err
+		}
+
+		if len(changes) == 0 {
+			p.createPost(userID, channelID, instance, fmt.Sprintf("No changes in the build #%d of the job '%s'.", buildNumber, jobName))
+			return nil
+		}
+		p.createPost(userID, channelID, instance, fmt.Sprintf("%d change(s) in the build #%d of the job '%s'\n%s", len(changes), buildNumber, jobName, formatBuildChanges(changes, false)))
+		return nil
+	}
+
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return err
+	}
+
+	lastBuildNumber := job.Raw.LastBuild.Number
+	buildNumber := lastBuildNumber
+	if buildID != "" {
+		buildNumber, _ = strconv.ParseInt(buildID, 10, 64)
+	}
+
+	if since >= buildNumber {
+		p.createEphemeralPost(userID, channelID, fmt.Sprintf("The build #%d is not older than the build #%d of the job '%s'.", since, buildNumber, jobName))
+		return nil
+	}
+	if buildNumber-since > maxChangesBuilds {
+		p.createEphemeralPost(userID, channelID, fmt.Sprintf("Changes can be aggregated across %d builds at most.", maxChangesBuilds))
+		return nil
+	}
+
+	// The builds are listed from the newest to the oldest. Deleted builds only move the builds after since
+	// closer to the start of the list, so they are all within the builds numbered after since.
+	count := lastBuildNumber - since
+	if count > maxChangesScannedBuilds {
+		p.createEphemeralPost(userID, channelID, fmt.Sprintf("Changes can only be looked for in the last %d builds of the job.", maxChangesScannedBuilds))
+		return nil
+	}
+
+	// Changes are listed from the newest build to the oldest, like the builds of the job.
+	changes, err := getBuildsChanges(job, since, buildNumber, count)
+	if err != nil {
+		return err
+	}
+
+	if len(changes) == 0 {
+		p.createPost(userID, channelID, instance, fmt.Sprintf("No changes since the build #%d of the job '%s', up to the build #%d.", since, jobName, buildNumber))
+		return nil
+	}
+	p.createPost(userID, channelID, instance, fmt.Sprintf("%d change(s) since the build #%d of the job '%s', up to the build #%d\n%s", len(changes), since, jobName, buildNumber, formatBuildChanges(changes, true)))
+	return nil
+}
+
+func (p *Plugin) executeChangesCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	parameters, sinceValue, ok := extractFlag(parameters, "since")
+	if !ok {
+		return p.getCommandResponse(args, "Please specify a build number after `--since`.")
+	}
+
+	var since int64
+	if sinceValue != "" {
+		var err error
+		since, err = strconv.ParseInt(sinceValue, 10, 64)
+		if err != nil || since < 1 {
+			return p.getCommandResponse(args, fmt.Sprintf("Invalid build number '%s'.", sinceValue))
+		}
+	}
+
+	jobName, buildNumber, extra, ok := parseBuildParameters(parameters)
+	if !ok || jobName == "" || len(extra) != 0 {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get the changes of a build.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	if err := p.postBuildChanges(args.UserId, args.ChannelId, jobName, buildNumber, since); err != nil {
+		p.API.LogError("Error fetching the changes of the build", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, fmt.Sprintf("Encountered an error while fetching the changes of the job '%s'.", jobName))
+	}
+
+	return &model.CommandResponse{}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waseem18/gojenkins"
+)
+
+func TestGetBuildChanges(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/job/freestyle/3/api/json":
+			_, _ = res.Write([]byte(`{"number": 3, "changeSet": {"items": [
+				{"commitId": "0123456789abcdef", "msg": "Fix the build", "author": {"fullName": "Jane Doe"}}]}}`))
+		case "/job/pipeline/7/api/json":
+			_, _ = res.Write([]byte(`{"number": 7, "changeSets": [
+				{"items": [{"commitId": "aaaaaaaa11", "comment": "Bump version\n\nLong description", "author": {"fullName": "John Doe"}}]},
+				{"items": [{"commitId": "bbbb", "msg": "Update docs", "author": {"fullName": "Jane Doe"}}]}]}`))
+		case "/job/freestyle/4/api/json":
+			_, _ = res.Write([]byte(`{"number": 4,
+				"changeSet": {"items": [{"commitId": "cccc", "msg": "Fix the tests", "author": {"fullName": "Jane Doe"}}]},
+				"changeSets": [{"items": [{"commitId": "cccc", "msg": "Fix the tests", "author": {"fullName": "Jane Doe"}}]}]}`))
+		default:
+			res.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	changes, err := getBuildChanges(jenkins, "/job/freestyle/4")
+	assert.Nil(t, err)
+	assert.Equal(t, []buildChange{{Build: 4, CommitID: "cccc", Author: "Jane Doe", Message: "Fix the tests"}}, changes)
+
+	changes, err = getBuildChanges(jenkins, "/job/freestyle/3")
+	assert.Nil(t, err)
+	assert.Equal(t, []buildChange{{Build: 3, CommitID: "0123456789abcdef", Author: "Jane Doe", Message: "Fix the build"}}, changes)
+
+	changes, err = getBuildChanges(jenkins, "/job/pipeline/7")
+	assert.Nil(t, err)
+	assert.Equal(t, []buildChange{
+		{Build: 7, CommitID: "aaaaaaaa11", Author: "John Doe", Message: "Bump version"},
+		{Build: 7, CommitID: "bbbb", Author: "Jane Doe", Message: "Update docs"},
+	}, changes)
+
+	changes, err = getBuildChanges(jenkins, "/job/pipeline/6")
+	assert.Nil(t, err)
+	assert.Nil(t, changes)
+}
+
+func TestGetBuildsChanges(t *testing.T) {
+	var tree string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/job/freestyle/api/json":
+			tree = req.URL.Query().Get("tree")
+			_, _ = res.Write([]byte(`{"allBuilds": [
+				{"number": 6, "changeSet": {"items": [{"commitId": "ffff", "msg": "Not included", "author": {"fullName": "Jane Doe"}}]}},
+				{"number": 5, "changeSet": {"items": [{"commitId": "eeee", "msg": "Fix the tests", "author": {"fullName": "Jane Doe"}}]},
+					"changeSets": [{"items": [{"commitId": "eeee", "msg": "Fix the tests", "author": {"fullName": "Jane Doe"}}]}]},
+				{"number": 3, "changeSet": {"items": [{"commitId": "cccc", "msg": "Fix the build", "author": {"fullName": "John Doe"}}]}},
+				{"number": 2, "changeSet": {"items": [{"commitId": "bbbb", "msg": "Not included either", "author": {"fullName": "John Doe"}}]}}]}`))
+		default:
+			res.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	changes, err := getBuildsChanges(&gojenkins.Job{Jenkins: jenkins, Base: "/job/freestyle"}, 2, 5, 4)
+	assert.Nil(t, err)
+	assert.Equal(t, []buildChange{
+		{Build: 5, CommitID: "eeee", Author: "Jane Doe", Message: "Fix the tests"},
+		{Build: 3, CommitID: "cccc", Author: "John Doe", Message: "Fix the build"},
+	}, changes)
+	assert.True(t, strings.HasPrefix(tree, "allBuilds["))
+	assert.True(t, strings.HasSuffix(tree, "]]{0,4}"))
+
+	_, err = getBuildsChanges(&gojenkins.Job{Jenkins: jenkins, Base: "/job/secret"}, 2, 5, 4)
+	assert.NotNil(t, err)
+}
+
+func TestFormatBuildChanges(t *testing.T) {
+	changes := []buildChange{
+		{Build: 7, CommitID: "aaaaaaaa11", Author: "John Doe", Message: "Bump version"},
+		{Build: 6, CommitID: "bbbb", Author: "Jane Doe", Message: "Update docs"},
+	}
+	assert.Equal(t, "\n* `aaaaaaaa` Bump version - John Doe\n* `bbbb` Update docs - Jane Doe", formatBuildChanges(changes, false))
+	assert.Equal(t, "\n* `aaaaaaaa` Bump version - John Doe (#7)\n* `bbbb` Update docs - Jane Doe (#6)", formatBuildChanges(changes, true))
+
+	many := []buildChange{}
+	for i := 0; i < maxListedChanges+5; i++ {
+		many = append(many, buildChange{CommitID: "c", Message: "m", Author: "a"})
+	}
+	formatted := formatBuildChanges(many, false)
+	assert.Equal(t, maxListedChanges+1, strings.Count(formatted, "\n* "))
+	assert.True(t, strings.HasSuffix(formatted, "\n* and 5 more"))
+}
@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"path"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
@@ -15,33 +17,102 @@ const helpText = `
 * |/jenkins connect username APIToken| - Connect your Mattermost account to Jenkins.
 * |/jenkins disconnect| - Disconnect your Mattermost account with Jenkins.
 
+###### Multiple Jenkins instances
+* Add |--instance name| to any command to run it against a named Jenkins instance configured in the plugin settings.
+  * For commands which take a jobname, the instance can also be specified as |instance:folder1/jobname|.
+  * Connect to each instance separately with |/jenkins connect username APIToken --instance name|.
+
 ###### Interact with Jenkins jobs
 * |/jenkins createjob| - Create a job using config.xml.
 * |/jenkins build jobname| - Trigger a build for the given job.
+  * The build is followed until it has finished and its result is posted to the channel.
+  * The posts of the build have buttons to abort it, get its log, test results and artifacts and rebuild it. Buttons use the Jenkins account of the user who clicks them.
   * If the job resides in a folder, specify the job as |folder1/jobname|. Note the slash character.
   * If the folder name or job name has spaces in it, wrap the jobname in double quotes as |"job name with space"| or |"folder with space/jobname"|.
   * Follow similar patterns for all commands which takes jobname as input.
   * Use double quotes only when there are spaces in the job name or folder name.
+* |/jenkins rebuild jobname <build number> <key=value>| - Trigger the job again with the parameters of a previous build.
+  * If build number is not specified, the parameters of the last build are used.
+  * Parameters given as |key=value| override the ones of the previous build.
 * |/jenkins abort jobname <build number>| - Abort the build of a given job.
   * If build number is not specified, the command aborts the last running build.
 * |/jenkins enable jobname| - Enanble a given job.
 * |/jenkins disable jobname| - Disable a given job.
-* |/jenkins delete jobname| - Deletes a given job.
-* |/jenkins get-artifacts jobname| - Get artifacts of the last build of the given job.
-* |/jenkins test-results jobname| - Get test results of the last build of the given job.
+* |/jenkins delete jobname| - Deletes a given job, after asking for confirmation.
+* |/jenkins get-artifacts jobname <build number>| - Get artifacts of a build of the given job. If build number is not specified, the artifacts of the last build are fetched.
+  * Use |--match '*.apk'| to only get the artifacts matching a glob pattern.
+  * Use |--zip| to bundle the artifacts into a single zip file.
+  * Artifacts larger than the size limits configured in the plugin settings are listed with their links instead of being uploaded.
+* |/jenkins test-results jobname <build number>| - Get a summary of the test results of a build of the given job: totals, failing tests and the changes since the previous build.
+  * If build number is not specified, the command fetches the test results of the last build.
 * |/jenkins get-log jobname <build number>| - Get build log of a given job. Build number is optional.
   * If build number is not specified, the command fetches the log of the last build.
+* |/jenkins flaky jobname| - List the tests which flipped between passing and failing in the last 10 builds of the given job, with their flip rate.
+  * Use |--builds N| to look at the last N builds instead, up to 50.
+* |/jenkins why-failed jobname <build number>| - Post the lines of the console output matching the error markers, with a few lines of context.
+  * If build number is not specified, the command checks the last build. An excerpt is also posted automatically when a followed build fails.
+* |/jenkins follow-log jobname <build number>| - Stream the console output of a running build into a thread until the build has finished.
+  * If build number is not specified, the command follows the last build.
+* |/jenkins stages jobname <build number>| - Get the stages of a Pipeline build with their status and duration. The failing stage is highlighted.
+  * If build number is not specified, the command fetches the stages of the last build.
+* |/jenkins input jobname <build number>| - Post the input steps a Pipeline build is paused on, with buttons to proceed or abort them.
+  * If build number is not specified, the command checks the last build. The input steps of the builds followed by the plugin are posted automatically.
+* |/jenkins changes jobname <build number>| - List the commits of a build of the given job with their author and message.
+  * If build number is not specified, the command lists the commits of the last build.
+  * Use |--since N| to list the commits of all the builds after the build N, up to 50 builds.
+* |/jenkins history jobname <N>| - List the last N builds of the given job with their result, duration, start time, cause and parameters. N defaults to 10, up to 50.
+* |/jenkins status jobname| - Get the status of a given job, including its last builds, health and estimated duration.
+* |/jenkins jobs <folder>| - List the jobs of a given folder and its nested folders, with their status. If folder is not specified, all the jobs are listed.
+  * Use |--filter regex| to only list the jobs whose full path matches the regular expression.
+  * Jobs are listed 50 per page. Use |--page N| to see the other pages.
+
+###### Inspect the build queue
+* |/jenkins queue| - List the items in the build queue, with the reason they are waiting and who queued them.
+* |/jenkins queue cancel <queue ID or jobname>| - Remove an item from the build queue. If a jobname is specified, all the queued builds of the job are removed.
+
+###### Subscribe to build events
+* |/jenkins subscribe jobname <events>| - Subscribe the channel to build events of a given job.
+  * Use |folder1/*| to subscribe to all jobs of a folder, including nested folders.
+  * Events is a comma separated list of |started|, |success|, |failure|, |unstable| and |aborted|. If not specified, all events are posted.
+  * Build events are received through the |/plugins/jenkins/webhook| endpoint from the Jenkins Notification plugin.
+* |/jenkins unsubscribe jobname| - Unsubscribe the channel from a given job.
+* |/jenkins subscriptions| - List the subscriptions of the channel.
+
+###### Channel aliases
+* |/jenkins alias add name jobname <key=value>| - Add a short name for a job to the channel, with preset build parameters.
+* |/jenkins alias list| - List the aliases of the channel.
+* |/jenkins alias remove name| - Remove an alias from the channel.
+* |/jenkins run name <key=value>| - Build the job of an alias with its preset parameters. Parameters given as |key=value| override the preset ones.
+
+###### Schedule builds
+* |/jenkins schedule jobname "cron" <key=value>| - Trigger a build of the given job periodically in the channel, with your Jenkins account.
+  * The cron expression has 5 fields: minute, hour, day of month, month and day of week, like |"0 2 * * 1-5"|. Macros like |@daily| are accepted too.
+  * The cron expression is evaluated in your timezone. Parameters are given as |key=value|.
+* |/jenkins schedules| - List the scheduled builds of the channel.
+* |/jenkins unschedule ID| - Remove a scheduled build of the channel.
+
+###### Manage nodes
+* |/jenkins nodes| - List the nodes with their state, busy and idle executors, labels and offline reason.
+* |/jenkins node offline nodename <reason>| - Take a node temporarily offline, for example for maintenance. Reason is optional.
+* |/jenkins node online nodename| - Bring a temporarily offline node back online.
 
 ###### Interact with Plugins
 * |/jenkins plugins| - Get a list of installed plugins on the Jenkins server.
 
 ###### Adhoc Commands
-* |/jenkins safe-restart| - Safe restarts the Jenkins server.
+* |/jenkins safe-restart| - Safe restarts the Jenkins server, after asking for confirmation.
 * |/jenkins me| - Display the connected Jenkins account.
 * |/jenkins help| - Find help related to the syntax of the slash commands.
 `
 const jobNotSpecifiedResponse = "Please specify a job name to build."
-const pollingSleepTime = 10
+const buildWatchTimeout = 24 * time.Hour
+
+// pollingInterval is the time between two polls of Jenkins while following builds.
+// It is a variable so that tests can shorten it.
+var pollingInterval = 10 * time.Second
+
+// commandNames are the subcommands of /jenkins.
+var commandNames = []string{"connect", "disconnect", "me", "build", "rebuild", "get-artifacts", "test-results", "flaky", "get-log", "follow-log", "why-failed", "stages", "input", "abort", "disable", "enable", "delete", "safe-restart", "plugins", "createjob", "status", "history", "changes", "jobs", "queue", "nodes", "node", "subscribe", "unsubscribe", "subscriptions", "schedule", "schedules", "unschedule", "alias", "run", "help"}
 
 func (p *Plugin) getCommand() (*model.Command, error) {
 	iconData, err := command.GetIconData(p.API, "assets/icon.svg")
@@ -55,7 +126,7 @@ func (p *Plugin) getCommand() (*model.Command, error) {
 		Description:          "A Mattermost plugin to interact with Jenkins",
 		DisplayName:          "Jenkins",
 		AutoComplete:         true,
-		AutoCompleteDesc:     "Available commands: connect, disconnect, me, build, get-artifacts, test-results, get-log, abort, disable, enable, delete, safe-restart, plugins, createjob, help",
+		AutoCompleteDesc:     "Available commands: " + strings.Join(commandNames, ", "),
 		AutoCompleteHint:     "[command]",
 		AutocompleteData:     getAutocompleteData(),
 		AutocompleteIconData: iconData,
@@ -74,31 +145,126 @@ func getAutocompleteData() *model.AutocompleteData {
 	createjob := model.NewAutocompleteData("createjob", "", "Create a Jenkins job using the contents of a config.xml file")
 
 	build := model.NewAutocompleteData("build", "[jobname]", "Trigger a build for a given job")
-	build.AddTextArgument("folder1/jobname if the job is in a folder, or \"job with space\"", "[jobname]", "")
+	build.AddDynamicListArgument("folder1/jobname if the job is in a folder, or \"job with space\"", "autocomplete/jobs", true)
 
 	abort := model.NewAutocompleteData("abort", "[jobname] <build number>", "Abort the given build of the specified job")
-	abort.AddTextArgument("Job associated with the build you want to abort", "[jobname]", "")
+	abort.AddDynamicListArgument("Job associated with the build you want to abort", "autocomplete/jobs", true)
 	abort.AddTextArgument("Build number to abort. If not specified, the last build is chosen", "<build number>", "")
 
 	enable := model.NewAutocompleteData("enable", "[jobname]", "Enable a given Jenkins job")
-	enable.AddTextArgument("The job you want to enable", "[jobname]", "")
+	enable.AddDynamicListArgument("The job you want to enable", "autocomplete/jobs", true)
 
 	disable := model.NewAutocompleteData("disable", "[jobname]", "Disable a given Jenkins job")
-	disable.AddTextArgument("The job you want to disable", "[jobname]", "")
+	disable.AddDynamicListArgument("The job you want to disable", "autocomplete/jobs", true)
 
 	delete := model.NewAutocompleteData("delete", "[jobname]", "Delete a given job")
-	delete.AddTextArgument("The job you want to delete", "[jobname]", "")
+	delete.AddDynamicListArgument("The job you want to delete", "autocomplete/jobs", true)
 
-	getArtifacts := model.NewAutocompleteData("get-artifacts", "[jobname]", "Get artifacts of the last build of the given job")
-	getArtifacts.AddTextArgument("The job you want to get artifacts from", "[jobname]", "")
+	getArtifacts := model.NewAutocompleteData("get-artifacts", "[jobname] <build number> <--zip>", "Get artifacts of a build of the given job. Add --zip to bundle them into a single zip file")
+	getArtifacts.AddDynamicListArgument("The job you want to get artifacts from", "autocomplete/jobs", true)
+	getArtifacts.AddTextArgument("Build number to get artifacts from. If not specified, the last build is chosen", "<build number>", "")
+	getArtifacts.AddNamedTextArgument("match", "Glob pattern the artifacts must match, such as '*.apk'", "[pattern]", "", false)
 
 	testResults := model.NewAutocompleteData("test-results", "[jobname]", "Get test results of the last build of the given job")
-	getArtifacts.AddTextArgument("The job you want to get test results from", "[jobname]", "")
+	testResults.AddDynamicListArgument("The job you want to get test results from", "autocomplete/jobs", true)
+
+	flaky := model.NewAutocompleteData("flaky", "[jobname]", "List the tests which flipped between passing and failing in the last builds of the given job")
+	flaky.AddDynamicListArgument("The job you want to find flaky tests of", "autocomplete/jobs", true)
+	flaky.AddNamedTextArgument("builds", "Number of builds to look at, 10 by default", "[number of builds]", "", false)
 
 	getLog := model.NewAutocompleteData("get-log", "[jobname] <build number>", "Get log of a build of the given job")
-	getLog.AddTextArgument("The job you want to get log from", "[jobname]", "")
+	getLog.AddDynamicListArgument("The job you want to get log from", "autocomplete/jobs", true)
 	getLog.AddTextArgument("Build number to get log from. If not specified, the last build is chosen", "<build number>", "")
 
+	followLog := model.NewAutocompleteData("follow-log", "[jobname] <build number>", "Stream the console output of a running build into a thread")
+	followLog.AddDynamicListArgument("The job you want to follow the log of", "autocomplete/jobs", true)
+	followLog.AddTextArgument("Build number to follow. If not specified, the last build is chosen", "<build number>", "")
+
+	whyFailed := model.NewAutocompleteData("why-failed", "[jobname] <build number>", "Post the error markers found in the console output of a build")
+	whyFailed.AddDynamicListArgument("The job of the failed build", "autocomplete/jobs", true)
+	whyFailed.AddTextArgument("Build number to check. If not specified, the last build is chosen", "<build number>", "")
+
+	rebuild := model.NewAutocompleteData("rebuild", "[jobname] <build number> <key=value>", "Trigger the job again with the parameters of a previous build")
+	rebuild.AddDynamicListArgument("The job you want to rebuild", "autocomplete/jobs", true)
+	rebuild.AddTextArgument("Build number to take the parameters from. If not specified, the last build is chosen", "<build number>", "")
+	rebuild.AddTextArgument("Parameters to override, as key=value", "<key=value>", "")
+
+	stages := model.NewAutocompleteData("stages", "[jobname] <build number>", "Get the stages of a Pipeline build with their status and duration")
+	stages.AddDynamicListArgument("The Pipeline job you want to get the stages of", "autocomplete/jobs", true)
+	stages.AddTextArgument("Build number to get the stages of. If not specified, the last build is chosen", "<build number>", "")
+
+	input := model.NewAutocompleteData("input", "[jobname] <build number>", "Post the input steps a Pipeline build is paused on")
+	input.AddDynamicListArgument("The Pipeline job waiting for input", "autocomplete/jobs", true)
+	input.AddTextArgument("Build number to check. If not specified, the last build is chosen", "<build number>", "")
+
+	queue := model.NewAutocompleteData("queue", "[cancel]", "List the items in the build queue")
+	queueCancel := model.NewAutocompleteData("cancel", "[queue ID or jobname]", "Remove an item from the build queue")
+	queueCancel.AddTextArgument("ID of the queue item, or a job to remove all its queued builds", "[queue ID or jobname]", "")
+	queue.AddCommand(queueCancel)
+
+	nodes := model.NewAutocompleteData("nodes", "", "List the nodes with their state and executors")
+
+	node := model.NewAutocompleteData("node", "[offline|online]", "Take a node offline or bring it back online")
+	nodeOffline := model.NewAutocompleteData("offline", "[nodename] <reason>", "Take a node temporarily offline")
+	nodeOffline.AddTextArgument("The node to take offline", "[nodename]", "")
+	nodeOffline.AddTextArgument("Why the node is taken offline", "<reason>", "")
+	nodeOnline := model.NewAutocompleteData("online", "[nodename]", "Bring a temporarily offline node back online")
+	nodeOnline.AddTextArgument("The node to bring back online", "[nodename]", "")
+	node.AddCommand(nodeOffline)
+	node.AddCommand(nodeOnline)
+
+	subscribe := model.NewAutocompleteData("subscribe", "[jobname] <events>", "Subscribe the channel to build events of a given job")
+	subscribe.AddTextArgument("The job to subscribe to, or folder1/* for all jobs of a folder", "[jobname]", "")
+	subscribe.AddTextArgument("Comma separated list of started, success, failure, unstable, aborted. If not specified, all events are posted", "<events>", "")
+
+	unsubscribe := model.NewAutocompleteData("unsubscribe", "[jobname]", "Unsubscribe the channel from a given job")
+	unsubscribe.AddTextArgument("The job to unsubscribe from", "[jobname]", "")
+
+	subscriptions := model.NewAutocompleteData("subscriptions", "", "List the subscriptions of the channel")
+
+	alias := model.NewAutocompleteData("alias", "[add|list|remove]", "Manage the job aliases of the channel")
+	aliasAdd := model.NewAutocompleteData("add", "[name] [jobname] <key=value>", "Add a short name for a job to the channel, with preset build parameters")
+	aliasAdd.AddTextArgument("Name of the alias", "[name]", "")
+	aliasAdd.AddDynamicListArgument("The job of the alias", "autocomplete/jobs", true)
+	aliasAdd.AddTextArgument("Preset build parameters, as key=value", "<key=value>", "")
+	aliasList := model.NewAutocompleteData("list", "", "List the aliases of the channel")
+	aliasRemove := model.NewAutocompleteData("remove", "[name]", "Remove an alias from the channel")
+	aliasRemove.AddTextArgument("Name of the alias", "[name]", "")
+	alias.AddCommand(aliasAdd)
+	alias.AddCommand(aliasList)
+	alias.AddCommand(aliasRemove)
+
+	run := model.NewAutocompleteData("run", "[name] <key=value>", "Build the job of an alias with its preset parameters")
+	run.AddTextArgument("Name of the alias", "[name]", "")
+	run.AddTextArgument("Build parameters overriding the preset ones, as key=value", "<key=value>", "")
+
+	schedule := model.NewAutocompleteData("schedule", "[jobname] [\"cron\"] <key=value>", "Trigger a build of the given job periodically in the channel")
+	schedule.AddDynamicListArgument("The job to schedule", "autocomplete/jobs", true)
+	schedule.AddTextArgument("Quoted cron expression with 5 fields, evaluated in your timezone", "[\"cron\"]", "")
+	schedule.AddTextArgument("Build parameters, as key=value", "<key=value>", "")
+
+	schedules := model.NewAutocompleteData("schedules", "", "List the scheduled builds of the channel")
+
+	unschedule := model.NewAutocompleteData("unschedule", "[ID]", "Remove a scheduled build of the channel")
+	unschedule.AddTextArgument("ID of the schedule, as listed by /jenkins schedules", "[ID]", "")
+
+	status := model.NewAutocompleteData("status", "[jobname]", "Get the status of a given job")
+	status.AddDynamicListArgument("The job you want to get the status of", "autocomplete/jobs", true)
+
+	changes := model.NewAutocompleteData("changes", "[jobname] <build number>", "List the commits of a build of the given job")
+	changes.AddDynamicListArgument("The job you want to get the changes of", "autocomplete/jobs", true)
+	changes.AddTextArgument("Build number to get the changes of. If not specified, the last build is chosen", "<build number>", "")
+	changes.AddNamedTextArgument("since", "List the commits of all the builds after this build", "[build number]", "", false)
+
+	history := model.NewAutocompleteData("history", "[jobname] <number of builds>", "List the last builds of the given job")
+	history.AddDynamicListArgument("The job you want to get the build history of", "autocomplete/jobs", true)
+	history.AddTextArgument("Number of builds to list, 10 by default", "<number of builds>", "")
+
+	jobs := model.NewAutocompleteData("jobs", "<folder>", "List the jobs of a given folder and its nested folders")
+	jobs.AddTextArgument("The folder to list the jobs of. If not specified, all the jobs are listed", "<folder>", "")
+	jobs.AddNamedTextArgument("filter", "Regular expression the full path of the jobs must match", "[regex]", "", false)
+	jobs.AddNamedTextArgument("page", "Page of the list to display", "[page number]", "", false)
+
 	plugins := model.NewAutocompleteData("plugins", "", "Get a list of installed plugins on the Jenkins server")
 
 	safeRestart := model.NewAutocompleteData("safe-restart", "", "Safe restart of the Jenkins server")
@@ -107,21 +273,46 @@ func getAutocompleteData() *model.AutocompleteData {
 
 	help := model.NewAutocompleteData("help", "", "Find help related to the syntax of the slash commands")
 
+	for _, command := range []*model.AutocompleteData{abort, aliasAdd, build, changes, connect, createjob, delete, disable, disconnect, enable, flaky, followLog, getArtifacts, getLog, history, input, jobs, me, nodeOffline, nodeOnline, nodes, plugins, queue, queueCancel, rebuild, safeRestart, schedule, stages, status, subscribe, testResults, unsubscribe, whyFailed} {
+		command.AddNamedTextArgument("instance", "Name of the Jenkins instance. If not specified, the default instance is used", "[instance name]", "", false)
+	}
+
 	jenkins.AddCommand(abort)
+	jenkins.AddCommand(alias)
 	jenkins.AddCommand(build)
+	jenkins.AddCommand(changes)
 	jenkins.AddCommand(connect)
 	jenkins.AddCommand(createjob)
 	jenkins.AddCommand(delete)
 	jenkins.AddCommand(disable)
 	jenkins.AddCommand(disconnect)
 	jenkins.AddCommand(enable)
+	jenkins.AddCommand(flaky)
+	jenkins.AddCommand(followLog)
 	jenkins.AddCommand(getArtifacts)
 	jenkins.AddCommand(getLog)
 	jenkins.AddCommand(help)
+	jenkins.AddCommand(history)
+	jenkins.AddCommand(input)
+	jenkins.AddCommand(jobs)
 	jenkins.AddCommand(me)
+	jenkins.AddCommand(node)
+	jenkins.AddCommand(nodes)
 	jenkins.AddCommand(plugins)
+	jenkins.AddCommand(queue)
+	jenkins.AddCommand(rebuild)
+	jenkins.AddCommand(run)
 	jenkins.AddCommand(safeRestart)
+	jenkins.AddCommand(schedule)
+	jenkins.AddCommand(schedules)
+	jenkins.AddCommand(stages)
+	jenkins.AddCommand(status)
+	jenkins.AddCommand(subscribe)
+	jenkins.AddCommand(subscriptions)
 	jenkins.AddCommand(testResults)
+	jenkins.AddCommand(unschedule)
+	jenkins.AddCommand(unsubscribe)
+	jenkins.AddCommand(whyFailed)
 	return jenkins
 }
 
@@ -155,13 +346,28 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 	if command != "/jenkins" {
 		return &model.CommandResponse{}, nil
 	}
+
+	parameters, instance, ok := extractFlag(parameters, "instance")
+	if !ok {
+		return p.getCommandResponse(args, "Please specify the name of the Jenkins instance after `--instance`."), nil
+	}
+	if instance != "" {
+		if _, err := p.getConfiguration().getInstanceURL(instance); err != nil {
+			return p.getCommandResponse(args, fmt.Sprintf("Unknown Jenkins instance '%s'.", instance)), nil
+		}
+	}
+
+	if allowed, denial := p.checkCommandPermission(args.UserId, args.ChannelId, action); !allowed {
+		return p.getCommandResponse(args, denial), nil
+	}
+
 	switch action {
 	case "connect":
 		if len(parameters) == 0 || len(parameters) == 1 {
 			return p.getCommandResponse(args, "Please specify both username and API token."), nil
 		} else if len(parameters) == 2 {
 			p.createEphemeralPost(args.UserId, args.ChannelId, "Validating Jenkins credentials...")
-			_, verifyErr := p.verifyJenkinsCredentials(parameters[0], parameters[1])
+			_, verifyErr := p.verifyJenkinsCredentials(parameters[0], parameters[1], instance)
 			if verifyErr != nil {
 				p.API.LogError("Error connecting to Jenkins", "user_id", args.UserId, "Err", verifyErr.Error())
 				return p.getCommandResponse(args, "Error connecting to Jenkins."), nil
@@ -169,6 +375,7 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 
 			jenkinsUserInfo := &JenkinsUserInfo{
 				UserID:   args.UserId,
+				Instance: instance,
 				Username: parameters[0],
 				Token:    parameters[1],
 			}
@@ -179,14 +386,30 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 				return &model.CommandResponse{}, nil
 			}
 
+			if instance != "" {
+				return p.getCommandResponse(args, fmt.Sprintf("Your account on the Jenkins instance '%s' has been successfully connected to Mattermost.", instance)), nil
+			}
 			return p.getCommandResponse(args, "Your Jenkins account has been successfully connected to Mattermost."), nil
 		}
 	case "build":
-		response, appError, done := p.executeBuildCommand(parameters, args)
+		response, appError, done := p.executeBuildCommand(parameters, instance, args)
 		if done {
 			return response, appError
 		}
+	case "rebuild":
+		return p.executeRebuildCommand(parameters, instance, args), nil
 	case "get-artifacts":
+		var options artifactOptions
+		parameters, options.Zip = extractBoolFlag(parameters, "zip")
+		parameters, options.Match, ok = extractFlag(parameters, "match")
+		if !ok {
+			return p.getCommandResponse(args, "Please specify a pattern after `--match`."), nil
+		}
+		options.Match = strings.Trim(options.Match, "'\"")
+		if _, err := path.Match(options.Match, ""); err != nil {
+			return p.getCommandResponse(args, fmt.Sprintf("Invalid pattern '%s'.", options.Match)), nil
+		}
+
 		if len(parameters) == 0 {
 			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
 		} else if len(parameters) >= 1 {
@@ -194,6 +417,10 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 			if !ok {
 				return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get artifacts of a build."), nil
 			}
+			jobName, ok = qualifyJobSelector(instance, jobName)
+			if !ok {
+				return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName)), nil
+			}
 			msg := ""
 			if buildNumber == "" {
 				msg = fmt.Sprintf("Fetching artifacts of the last build of the job '%s'...", jobName)
@@ -203,7 +430,7 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 				p.createEphemeralPost(args.UserId, args.ChannelId, msg)
 			}
 
-			if err := p.fetchAndUploadArtifactsOfABuild(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
+			if err := p.fetchAndUploadArtifactsOfABuild(args.UserId, args.ChannelId, jobName, buildNumber, options); err != nil {
 				p.API.LogError("Error fetching artifacts", "job_name", parameters[0], "err", err.Error())
 				return p.getCommandResponse(args, "Error fetching artifacts."), nil
 			}
@@ -216,6 +443,10 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 			if !ok {
 				return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get test results of a build."), nil
 			}
+			jobName, ok = qualifyJobSelector(instance, jobName)
+			if !ok {
+				return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName)), nil
+			}
 			msg := ""
 			if buildNumber == "" {
 				msg = fmt.Sprintf("Fetching test results of the last build of the job '%s'...", jobName)
@@ -225,7 +456,7 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 				p.createEphemeralPost(args.UserId, args.ChannelId, msg)
 			}
 
-			if err := p.getBuildTestResultsURL(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
+			if err := p.postBuildTestResults(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
 				p.API.LogError("Error fetching test results", "job_name", jobName, "err", err.Error())
 				return p.getCommandResponse(args, "Error fetching test results."), nil
 			}
@@ -238,12 +469,16 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 			if !ok || extraParam != "" {
 				return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to disable a job."), nil
 			}
+			jobName, ok = qualifyJobSelector(instance, jobName)
+			if !ok {
+				return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName)), nil
+			}
 
 			if err := p.disableJob(args.UserId, jobName); err != nil {
 				p.API.LogError("Error disabling the job.", "job_name", jobName, "err", err.Error())
 				return p.getCommandResponse(args, "Error disabling the job."), nil
 			}
-			p.createPost(args.UserId, args.ChannelId, fmt.Sprintf("Job '%s' has been disabled", jobName))
+			p.createPost(args.UserId, args.ChannelId, jobInstance(jobName), fmt.Sprintf("Job '%s' has been disabled", jobName))
 		}
 	case "enable":
 		if len(parameters) == 0 {
@@ -253,11 +488,15 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 			if !ok || extraParam != "" {
 				return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to enable a job."), nil
 			}
+			jobName, ok = qualifyJobSelector(instance, jobName)
+			if !ok {
+				return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName)), nil
+			}
 			if err := p.enableJob(args.UserId, jobName); err != nil {
 				p.API.LogError("Error enabling the job.", "job_name", jobName, "err", err.Error())
 				return p.getCommandResponse(args, "Error enabling the job."), nil
 			}
-			p.createPost(args.UserId, args.ChannelId, fmt.Sprintf("Job '%s' has been enabled", jobName))
+			p.createPost(args.UserId, args.ChannelId, jobInstance(jobName), fmt.Sprintf("Job '%s' has been enabled", jobName))
 		}
 	case "help":
 		text := "###### Mattermost Jenkins Plugin - Slash Command Help\n" + strings.ReplaceAll(helpText, "|", "`")
@@ -266,20 +505,23 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		text := "###### Mattermost Jenkins Plugin - Slash Command Help\n" + strings.ReplaceAll(helpText, "|", "`")
 		return p.getCommandResponse(args, text), nil
 	case "me":
-		userInfo, err := p.getJenkinsUserInfo(args.UserId)
+		userInfo, err := p.getJenkinsUserInfo(args.UserId, instance)
 		if err != nil {
 			p.API.LogError("Error fetching Jenkins user details", "err", err.Error())
 			return p.getCommandResponse(args, "Encountered an error getting your Jenkins user information."), nil
 		}
+		if instance != "" {
+			return p.getCommandResponse(args, fmt.Sprintf("You are connected to the Jenkins instance '%s' as: %s", instance, userInfo.Username)), nil
+		}
 		return p.getCommandResponse(args, fmt.Sprintf("You are connected to Jenkins as: %s", userInfo.Username)), nil
 	case "disconnect":
-		userInfo, err := p.getJenkinsUserInfo(args.UserId)
+		userInfo, err := p.getJenkinsUserInfo(args.UserId, instance)
 		if err != nil {
 			p.API.LogError("Error fetching Jenkins user details", "err", err.Error())
 			return p.getCommandResponse(args, "Encountered an error getting your Jenkins user information."), nil
 		}
 
-		if err := p.API.KVDelete(args.UserId + jenkinsTokenKey); err != nil {
+		if err := p.API.KVDelete(jenkinsUserInfoKey(args.UserId, instance)); err != nil {
 			p.API.LogError("Error disconnecting the user", "err", err.Error())
 			return p.getCommandResponse(args, "Encountered an error while disconnecting the user from Jenkins."), nil
 		}
@@ -292,6 +534,10 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 			if !ok {
 				return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get log of a build."), nil
 			}
+			jobName, ok = qualifyJobSelector(instance, jobName)
+			if !ok {
+				return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName)), nil
+			}
 			p.createEphemeralPost(args.UserId, args.ChannelId, fmt.Sprintf("Fetching logs of job '%s'...", jobName))
 
 			if err := p.fetchAndUploadBuildLog(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
@@ -299,6 +545,20 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 				return p.getCommandResponse(args, "Encountered an error fetching logs."), nil
 			}
 		}
+	case "flaky":
+		return p.executeFlakyCommand(parameters, instance, args), nil
+	case "why-failed":
+		return p.executeWhyFailedCommand(parameters, instance, args), nil
+	case "follow-log":
+		return p.executeFollowLogCommand(parameters, instance, args), nil
+	case "changes":
+		return p.executeChangesCommand(parameters, instance, args), nil
+	case "history":
+		return p.executeHistoryCommand(parameters, instance, args), nil
+	case "stages":
+		return p.executeStagesCommand(parameters, instance, args), nil
+	case "input":
+		return p.executeInputCommand(parameters, instance, args), nil
 	case "abort":
 		if len(parameters) == 0 {
 			return p.getCommandResponse(args, "Please specify a job name or jobname and build number."), nil
@@ -307,6 +567,10 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 			if !ok {
 				return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to abort a build."), nil
 			}
+			jobName, ok = qualifyJobSelector(instance, jobName)
+			if !ok {
+				return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName)), nil
+			}
 
 			if err := p.abortBuild(args.UserId, jobName, buildNumber); err != nil {
 				p.API.LogError("Error aborting Jenkins build", "job_name", jobName, "err", err.Error())
@@ -319,7 +583,7 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 				msg = fmt.Sprintf("Build #%s of the job '%s' has been aborted.", buildNumber, jobName)
 			}
 
-			p.createPost(args.UserId, args.ChannelId, msg)
+			p.createPost(args.UserId, args.ChannelId, jobInstance(jobName), msg)
 		}
 	case "delete":
 		if len(parameters) == 0 {
@@ -329,28 +593,37 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 			if !ok || extraParam != "" {
 				return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to delete a job."), nil
 			}
+			jobName, ok = qualifyJobSelector(instance, jobName)
+			if !ok {
+				return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName)), nil
+			}
 
-			if err := p.deleteJob(args.UserId, jobName); err != nil {
-				p.API.LogError("Error deleting the job", "job_name", jobName, "err", err.Error())
+			if _, err := p.getJob(args.UserId, jobName); err != nil {
+				p.API.LogError("Error fetching the job to delete", "job_name", jobName, "err", err.Error())
 				return p.getCommandResponse(args, "Encountered an error while deleting the job."), nil
 			}
 
-			p.createPost(args.UserId, args.ChannelId, fmt.Sprintf("Job '%s' has been deleted.", jobName))
+			confirmation := &pendingConfirmation{UserID: args.UserId, ChannelID: args.ChannelId, Command: "delete", Job: jobName}
+			if err := p.requestConfirmation(confirmation); err != nil {
+				p.API.LogError("Error requesting the confirmation", "job_name", jobName, "err", err.Error())
+				return p.getCommandResponse(args, "Encountered an error while deleting the job."), nil
+			}
 		}
 	case "safe-restart":
 		if len(parameters) != 0 {
 			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to safe restart Jenkins."), nil
 		}
-		if err := p.safeRestart(args.UserId); err != nil {
-			p.API.LogError("Error while safe restarting the Jenkins server", err.Error())
+
+		confirmation := &pendingConfirmation{UserID: args.UserId, ChannelID: args.ChannelId, Command: "safe-restart", Instance: instance}
+		if err := p.requestConfirmation(confirmation); err != nil {
+			p.API.LogError("Error requesting the confirmation", "err", err.Error())
 			return p.getCommandResponse(args, "Encountered an error while safe restarting the Jenkins server."), nil
 		}
-		p.createPost(args.UserId, args.ChannelId, "Safe restart of Jenkins server has been triggered.")
 	case "plugins":
 		if len(parameters) != 0 {
 			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get a list of plugins."), nil
 		}
-		if err := p.getListOfInstalledPlugins(args.UserId, args.ChannelId); err != nil {
+		if err := p.getListOfInstalledPlugins(args.UserId, args.ChannelId, instance); err != nil {
 			p.API.LogError("Error while fetching list of installed plugins", err.Error())
 			return p.getCommandResponse(args, "Encountered an error while fetching list of installed plugins"), nil
 		}
@@ -358,10 +631,54 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 		if len(parameters) != 0 {
 			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to create a job."), nil
 		}
-		if err := p.createJob(args.UserId, args.ChannelId, args.TriggerId); err != nil {
+		if err := p.createJob(args.UserId, args.ChannelId, args.TriggerId, instance); err != nil {
 			p.API.LogError("Error while creating the job.", err.Error())
 			return p.getCommandResponse(args, "Encountered an error while creating the job"), nil
 		}
+	case "status":
+		jobName, extraParam, _, ok := parseBuildParameters(parameters)
+		if !ok || extraParam != "" {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get the status of a job."), nil
+		}
+		jobName, ok = qualifyJobSelector(instance, jobName)
+		if !ok {
+			return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName)), nil
+		}
+
+		if err := p.postJobStatus(args.UserId, args.ChannelId, jobName); err != nil {
+			p.API.LogError("Error fetching the job status", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error while fetching the status of the job."), nil
+		}
+	case "jobs":
+		return p.executeJobsCommand(parameters, instance, args), nil
+	case "nodes":
+		return p.executeNodesCommand(parameters, instance, args), nil
+	case "node":
+		return p.executeNodeCommand(parameters, instance, args), nil
+	case "queue":
+		return p.executeQueueCommand(parameters, instance, args), nil
+	case "subscribe":
+		return p.executeSubscribeCommand(parameters, instance, args), nil
+	case "unsubscribe":
+		return p.executeUnsubscribeCommand(parameters, instance, args), nil
+	case "subscriptions":
+		if len(parameters) != 0 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to list the subscriptions."), nil
+		}
+		return p.executeSubscriptionsCommand(args), nil
+	case "alias":
+		return p.executeAliasCommand(parameters, instance, args), nil
+	case "run":
+		return p.executeRunCommand(parameters, instance, args), nil
+	case "schedule":
+		return p.executeScheduleCommand(parameters, instance, args), nil
+	case "schedules":
+		if len(parameters) != 0 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to list the scheduled builds."), nil
+		}
+		return p.executeSchedulesCommand(args), nil
+	case "unschedule":
+		return p.executeUnscheduleCommand(parameters, args), nil
 	default:
 		text := "###### Unknown Command: " + action + "\n" + "###### Mattermost Jenkins Plugin - Slash Command Help\n" + strings.ReplaceAll(helpText, "|", "`")
 		return p.getCommandResponse(args, text), nil
@@ -369,7 +686,7 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 	return &model.CommandResponse{}, nil
 }
 
-func (p *Plugin) executeBuildCommand(parameters []string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError, bool) {
+func (p *Plugin) executeBuildCommand(parameters []string, instance string, args *model.CommandArgs) (*model.CommandResponse, *model.AppError, bool) {
 	if len(parameters) == 0 {
 		return p.getCommandResponse(args, jobNotSpecifiedResponse), nil, true
 	}
@@ -378,31 +695,42 @@ func (p *Plugin) executeBuildCommand(parameters []string, args *model.CommandArg
 		if !ok {
 			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get trigger a job."), nil, true
 		}
-
-		hasParameters, paramErr := p.checkIfJobAcceptsParameters(args.UserId, jobName)
-		if paramErr != nil {
-			p.API.LogError("Error checking for parameters", "err", paramErr.Error())
-			return p.getCommandResponse(args, fmt.Sprintf("Error triggering build for the job '%s'.", jobName)), nil, true
+		jobName, ok = qualifyJobSelector(instance, jobName)
+		if !ok {
+			return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName)), nil, true
 		}
 
-		if hasParameters && len(params) == 0 {
-			err := p.createDialogForParameters(args.UserId, args.TriggerId, jobName, args.ChannelId)
-			if err != nil {
-				p.API.LogError("Error creating dialog", "err", err.Error())
-				return p.getCommandResponse(args, fmt.Sprintf("Error triggering build for the job '%s'.", jobName)), nil, true
-			}
-		} else {
-			go func(job string, parameters map[string]string, userID, channelID string) {
-				build, err := p.triggerJenkinsJob(userID, channelID, job, parameters)
-				if err != nil {
-					p.API.LogError("Error triggering build", "job_name", job, "err", err.Error())
-					p.createPost(userID, channelID, fmt.Sprintf("Error triggering build for the job '%s'.", job))
-					return
-				}
-				p.createPost(userID, channelID, fmt.Sprintf("Job '%s' - #%d has been started\nBuild URL : %s", job, build.GetBuildNumber(), build.GetUrl()))
-			}(jobName, params, args.UserId, args.ChannelId)
-			return p.getCommandResponse(args, "Build triggered; check channel for updates."), nil, true
-		}
+		return p.startBuild(jobName, params, args), nil, true
 	}
 	return nil, nil, false
 }
+
+// startBuild triggers a build of the job with the given parameters and follows it in the background.
+// If no parameters are given and the job accepts some, a dialog is opened for the user to input them.
+func (p *Plugin) startBuild(jobName string, params map[string]string, args *model.CommandArgs) *model.CommandResponse {
+	hasParameters, paramErr := p.checkIfJobAcceptsParameters(args.UserId, jobName)
+	if paramErr != nil {
+		p.API.LogError("Error checking for parameters", "err", paramErr.Error())
+		return p.getCommandResponse(args, fmt.Sprintf("Error triggering build for the job '%s'.", jobName))
+	}
+
+	if hasParameters && len(params) == 0 {
+		err := p.createDialogForParameters(args.UserId, args.TriggerId, jobName, args.ChannelId)
+		if err != nil {
+			p.API.LogError("Error creating dialog", "err", err.Error())
+			return p.getCommandResponse(args, fmt.Sprintf("Error triggering build for the job '%s'.", jobName))
+		}
+		return &model.CommandResponse{}
+	}
+
+	go func(job string, parameters map[string]string, userID, channelID string) {
+		build, err := p.triggerJenkinsJob(userID, channelID, job, parameters)
+		if err != nil {
+			p.API.LogError("Error triggering build", "job_name", job, "err", err.Error())
+			p.createPost(userID, channelID, jobInstance(job), fmt.Sprintf("Error triggering build for the job '%s'.", job))
+			return
+		}
+		p.followBuild(userID, channelID, job, build)
+	}(jobName, params, args.UserId, args.ChannelId)
+	return p.getCommandResponse(args, "Build triggered; check channel for updates.")
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"net/url"
 	"path"
 	"reflect"
+	"regexp"
+	"strings"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/pkg/errors"
@@ -21,10 +24,20 @@ import (
 // copy appropriate for your types.
 type configuration struct {
 	JenkinsURL       string
+	JenkinsInstances string
 	Username         string
 	EncryptionKey    string
+	WebhookSecret    string
+	FailurePatterns  string
 	ProfileImageURL  string
 	PluginsDirectory string
+
+	// CommandPermissions restricts subcommands, one "command: rule, rule" per line.
+	CommandPermissions string
+
+	// MaxArtifactFileSize and MaxArtifactsRequestSize are in megabytes.
+	MaxArtifactFileSize     int
+	MaxArtifactsRequestSize int
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -34,6 +47,108 @@ func (c *configuration) Clone() *configuration {
 	return &clone
 }
 
+// instanceNameRegex matches the allowed names of Jenkins instances.
+var instanceNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// getInstances parses the named Jenkins instances from the plugin settings.
+// Each line of JenkinsInstances is expected in the name=URL format. Empty lines are ignored.
+func (c *configuration) getInstances() (map[string]string, error) {
+	instances := map[string]string{}
+	for _, line := range strings.Split(c.JenkinsInstances, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid Jenkins instance %q, expected name=URL", line)
+		}
+
+		name, instanceURL := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if !instanceNameRegex.MatchString(name) {
+			return nil, errors.Errorf("invalid Jenkins instance name %q, only letters, digits, dashes and underscores are allowed", name)
+		}
+
+		if _, ok := instances[name]; ok {
+			return nil, errors.Errorf("duplicate Jenkins instance %q", name)
+		}
+
+		if err := validateJenkinsURL(instanceURL); err != nil {
+			return nil, errors.Wrapf(err, "invalid URL of the Jenkins instance %q", name)
+		}
+
+		instances[name] = instanceURL
+	}
+
+	return instances, nil
+}
+
+// defaultFailurePatterns are the error markers searched in the console output of failed builds
+// when no failure patterns are configured.
+var defaultFailurePatterns = []string{"ERROR", "FAILED", "Exception", "exit code"}
+
+// getFailurePatterns parses the regular expressions matching error markers in console output.
+// Each line of FailurePatterns is a regular expression. Empty lines are ignored.
+func (c *configuration) getFailurePatterns() ([]*regexp.Regexp, error) {
+	lines := strings.Split(c.FailurePatterns, "\n")
+	if strings.TrimSpace(c.FailurePatterns) == "" {
+		lines = defaultFailurePatterns
+	}
+
+	patterns := []*regexp.Regexp{}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		pattern, err := regexp.Compile(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid failure pattern %q", line)
+		}
+		patterns = append(patterns, pattern)
+	}
+
+	return patterns, nil
+}
+
+// getInstanceURL returns the URL of the given Jenkins instance.
+// The Jenkins URL setting is used when the instance name is empty.
+func (c *configuration) getInstanceURL(instance string) (string, error) {
+	if instance == "" {
+		if c.JenkinsURL == "" {
+			return "", errors.New("no default Jenkins instance is configured, please specify an instance")
+		}
+		return c.JenkinsURL, nil
+	}
+
+	instances, err := c.getInstances()
+	if err != nil {
+		return "", err
+	}
+
+	instanceURL, ok := instances[instance]
+	if !ok {
+		return "", errors.Errorf("unknown Jenkins instance %q", instance)
+	}
+
+	return instanceURL, nil
+}
+
+func validateJenkinsURL(jenkinsURL string) error {
+	u, err := url.Parse(jenkinsURL)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme == "" {
+		return errors.New("please add scheme to the URL. HTTP or HTTPS")
+	}
+
+	return nil
+}
+
 // getConfiguration retrieves the active configuration under lock, making it safe to use
 // concurrently. The active configuration may change underneath the client of this method, but
 // the struct returned by this API call is considered immutable.
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetInstances(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Input    string
+		Expected map[string]string
+		Valid    bool
+	}{
+		"empty": {
+			Input:    "",
+			Expected: map[string]string{},
+			Valid:    true,
+		},
+		"multiple instances": {
+			Input:    "ci=https://ci.example.com\n\n release = https://release.example.com/jenkins \n",
+			Expected: map[string]string{"ci": "https://ci.example.com", "release": "https://release.example.com/jenkins"},
+			Valid:    true,
+		},
+		"missing URL": {
+			Input: "ci",
+			Valid: false,
+		},
+		"missing scheme": {
+			Input: "ci=ci.example.com",
+			Valid: false,
+		},
+		"invalid name": {
+			Input: "ci:1=https://ci.example.com",
+			Valid: false,
+		},
+		"duplicate name": {
+			Input: "ci=https://ci.example.com\nci=https://ci2.example.com",
+			Valid: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := &configuration{JenkinsInstances: tc.Input}
+			instances, err := c.getInstances()
+			assert.Equal(t, tc.Valid, err == nil)
+			if tc.Valid {
+				assert.Equal(t, tc.Expected, instances)
+			}
+		})
+	}
+}
+
+func TestGetInstanceURL(t *testing.T) {
+	c := &configuration{
+		JenkinsURL:       "https://jenkins.example.com",
+		JenkinsInstances: "ci=https://ci.example.com",
+	}
+
+	instanceURL, err := c.getInstanceURL("")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://jenkins.example.com", instanceURL)
+
+	instanceURL, err = c.getInstanceURL("ci")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://ci.example.com", instanceURL)
+
+	_, err = c.getInstanceURL("release")
+	assert.NotNil(t, err)
+
+	c.JenkinsURL = ""
+	_, err = c.getInstanceURL("")
+	assert.NotNil(t, err)
+}
+
+func TestGetFailurePatterns(t *testing.T) {
+	c := &configuration{}
+	patterns, err := c.getFailurePatterns()
+	assert.Nil(t, err)
+	assert.Len(t, patterns, len(defaultFailurePatterns))
+
+	c = &configuration{FailurePatterns: "^FATAL\n\n npm ERR! \n"}
+	patterns, err = c.getFailurePatterns()
+	assert.Nil(t, err)
+	assert.Len(t, patterns, 2)
+	assert.True(t, patterns[1].MatchString("npm ERR! missing script"))
+
+	c = &configuration{FailurePatterns: "[unclosed"}
+	_, err = c.getFailurePatterns()
+	assert.NotNil(t, err)
+}
+
+func TestGetCommandPermissions(t *testing.T) {
+	c := &configuration{CommandPermissions: "delete: system_admin, @alice, group:release-managers, ~engineering/ops\n\n Safe-Restart : system_admin \n"}
+	permissions, err := c.getCommandPermissions()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]permissionRule{
+		"delete": {
+			{Kind: permissionRuleRole, Value: "system_admin"},
+			{Kind: permissionRuleUser, Value: "alice"},
+			{Kind: permissionRuleGroup, Value: "release-managers"},
+			{Kind: permissionRuleChannel, Value: "engineering/ops"},
+		},
+		"safe-restart": {{Kind: permissionRuleRole, Value: "system_admin"}},
+	}, permissions)
+
+	for name, value := range map[string]string{
+		"missing colon":     "delete system_admin",
+		"no rules":          "delete: ,",
+		"invalid rule":      "delete: @",
+		"channel no team":   "delete: ~ops",
+		"invalid channel":   "delete: ~engineering/",
+		"invalid command":   "delete job: system_admin",
+		"unknown command":   "safe-restrat: system_admin",
+		"duplicate command": "delete: system_admin\ndelete: @alice",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := (&configuration{CommandPermissions: value}).getCommandPermissions()
+			assert.NotNil(t, err)
+		})
+	}
+}
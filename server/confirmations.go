@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	actionConfirm = "confirm"
+	actionCancel  = "cancel"
+
+	jenkinsConfirmationKey = "_jenkinsConfirmation_"
+
+	// confirmationExpirySeconds is how long a destructive command waits for its confirmation.
+	confirmationExpirySeconds = 5 * 60
+)
+
+// pendingConfirmation is a destructive command waiting for the user who ran it to confirm it.
+type pendingConfirmation struct {
+	ID        string
+	UserID    string
+	ChannelID string
+	Command   string
+	Job       string
+	Instance  string
+}
+
+// confirmationKey returns the KV store key of a pending confirmation.
+func confirmationKey(id string) string {
+	return jenkinsConfirmationKey + id
+}
+
+// Description describes what the command does, for the confirmation prompt.
+func (c *pendingConfirmation) Description() string {
+	switch c.Command {
+	case "delete":
+		return fmt.Sprintf("delete the job '%s'", c.Job)
+	case "safe-restart":
+		if c.Instance != "" {
+			return fmt.Sprintf("safe restart the Jenkins instance '%s'", c.Instance)
+		}
+		return "safe restart the Jenkins server"
+	default:
+		return fmt.Sprintf("run `/jenkins %s`", c.Command)
+	}
+}
+
+// confirmationAction creates a button which confirms or cancels a pending confirmation.
+func (p *Plugin) confirmationAction(name, action, style, id string) *model.PostAction {
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	return &model.PostAction{
+		Name:  name,
+		Type:  model.PostActionTypeButton,
+		Style: style,
+		Integration: &model.PostActionIntegration{
+			URL: fmt.Sprintf("%s/plugins/jenkins/action", siteURL),
+			Context: map[string]interface{}{
+				"action":       action,
+				"confirmation": id,
+			},
+		},
+	}
+}
+
+// requestConfirmation stores the destructive command and asks the user to confirm it with an ephemeral post.
+// The command expires if it is not confirmed within confirmationExpirySeconds.
+func (p *Plugin) requestConfirmation(confirmation *pendingConfirmation) error {
+	confirmation.ID = model.NewId()
+
+	confirmationBytes, err := json.Marshal(confirmation)
+	if err != nil {
+		return err
+	}
+	if appErr := p.API.KVSetWithExpiry(confirmationKey(confirmation.ID), confirmationBytes, confirmationExpirySeconds); appErr != nil {
+		return errors.Wrap(appErr, "Error storing the pending confirmation")
+	}
+
+	attachment := generateSlackAttachment(fmt.Sprintf("Are you sure you want to %s? This can't be undone.\nThe confirmation expires in %d minutes.", confirmation.Description(), confirmationExpirySeconds/60))
+	attachment.Color = buildResultColor("FAILURE")
+	attachment.Actions = []*model.PostAction{
+		p.confirmationAction("Confirm", actionConfirm, "danger", confirmation.ID),
+		p.confirmationAction("Cancel", actionCancel, "default", confirmation.ID),
+	}
+
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: confirmation.ChannelID,
+		Props: map[string]interface{}{
+			"attachments": []*model.SlackAttachment{attachment},
+		},
+	}
+	p.API.SendEphemeralPost(confirmation.UserID, post)
+	return nil
+}
+
+// errConfirmationNotOwned is returned when a user tries to confirm the command of another user.
+var errConfirmationNotOwned = errors.New("the confirmation belongs to another user")
+
+// takeConfirmation fetches and removes a pending confirmation of the user, so that it runs once at most.
+// Returns nil if the confirmation has expired or was already used.
+func (p *Plugin) takeConfirmation(id, userID string) (*pendingConfirmation, error) {
+	confirmationBytes, appErr := p.API.KVGet(confirmationKey(id))
+	if appErr != nil {
+		return nil, appErr
+	}
+	if confirmationBytes == nil {
+		return nil, nil
+	}
+
+	confirmation := &pendingConfirmation{}
+	if err := json.Unmarshal(confirmationBytes, confirmation); err != nil {
+		return nil, err
+	}
+	if confirmation.UserID != userID {
+		return nil, errConfirmationNotOwned
+	}
+
+	deleted, appErr := p.API.KVCompareAndDelete(confirmationKey(id), confirmationBytes)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !deleted {
+		return nil, nil
+	}
+
+	return confirmation, nil
+}
+
+// handleConfirmationAction confirms or cancels a pending destructive command when its buttons are clicked.
+func (p *Plugin) handleConfirmationAction(w http.ResponseWriter, userID string, request *model.PostActionIntegrationRequest) {
+	action, _ := request.Context["action"].(string)
+	id, _ := request.Context["confirmation"].(string)
+	if id == "" {
+		http.Error(w, "Confirmation ID is missing", http.StatusBadRequest)
+		return
+	}
+
+	var response model.PostActionIntegrationResponse
+	confirmation, err := p.takeConfirmation(id, userID)
+	switch {
+	case err == errConfirmationNotOwned:
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	case err != nil:
+		p.API.LogError("Error fetching the pending confirmation", "err", err.Error())
+		response.EphemeralText = "Encountered an error while fetching the pending confirmation."
+	case confirmation == nil:
+		response.EphemeralText = "This confirmation has expired. Please run the command again."
+	case action == actionCancel:
+		response.EphemeralText = fmt.Sprintf("Cancelled, the command to %s has not been run.", confirmation.Description())
+	default:
+		if allowed, denial := p.checkCommandPermission(userID, confirmation.ChannelID, confirmation.Command); !allowed {
+			response.EphemeralText = denial
+			break
+		}
+
+		if err := p.runConfirmedCommand(confirmation); err != nil {
+			p.API.LogError("Error running the confirmed command", "command", confirmation.Command, "job_name", confirmation.Job, "err", err.Error())
+			response.EphemeralText = fmt.Sprintf("Encountered an error while trying to %s.", confirmation.Description())
+		}
+	}
+
+	// The prompt is removed so that its buttons can't be clicked again.
+	if request.PostId != "" {
+		p.API.DeleteEphemeralPost(userID, request.PostId)
+	}
+
+	b, _ := json.Marshal(response)
+	_, _ = w.Write(b)
+}
+
+// runConfirmedCommand runs a destructive command once the user confirmed it.
+func (p *Plugin) runConfirmedCommand(confirmation *pendingConfirmation) error {
+	switch confirmation.Command {
+	case "delete":
+		if err := p.deleteJob(confirmation.UserID, confirmation.Job); err != nil {
+			return err
+		}
+		p.createPost(confirmation.UserID, confirmation.ChannelID, jobInstance(confirmation.Job), fmt.Sprintf("Job '%s' has been deleted.", confirmation.Job))
+	case "safe-restart":
+		if err := p.safeRestart(confirmation.UserID, confirmation.Instance); err != nil {
+			return err
+		}
+		p.createPost(confirmation.UserID, confirmation.ChannelID, confirmation.Instance, "Safe restart of Jenkins server has been triggered.")
+	default:
+		return errors.Errorf("unknown command %q", confirmation.Command)
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRequestConfirmation(t *testing.T) {
+	p, api := setupTestPlugin(t, "https://jenkins.example.com")
+
+	siteURL := "https://mattermost.example.com"
+	config := &model.Config{}
+	config.ServiceSettings.SiteURL = &siteURL
+	api.On("GetConfig").Return(config)
+
+	var stored []byte
+	api.On("KVSetWithExpiry", mock.MatchedBy(func(key string) bool {
+		return strings.HasPrefix(key, jenkinsConfirmationKey)
+	}), mock.Anything, int64(confirmationExpirySeconds)).Run(func(args mock.Arguments) {
+		stored = args.Get(1).([]byte)
+	}).Return(nil)
+
+	var post *model.Post
+	api.On("SendEphemeralPost", "user1", mock.Anything).Run(func(args mock.Arguments) {
+		post = args.Get(1).(*model.Post)
+	}).Return(nil)
+
+	confirmation := &pendingConfirmation{UserID: "user1", ChannelID: "channel1", Command: "delete", Job: "jobname"}
+	assert.Nil(t, p.requestConfirmation(confirmation))
+	assert.NotEmpty(t, confirmation.ID)
+
+	storedConfirmation := &pendingConfirmation{}
+	assert.Nil(t, json.Unmarshal(stored, storedConfirmation))
+	assert.Equal(t, confirmation, storedConfirmation)
+
+	assert.Equal(t, "channel1", post.ChannelId)
+	attachment := post.Props["attachments"].([]*model.SlackAttachment)[0]
+	assert.Contains(t, attachment.Text, "Are you sure you want to delete the job 'jobname'?")
+	assert.Len(t, attachment.Actions, 2)
+	assert.Equal(t, "Confirm", attachment.Actions[0].Name)
+	assert.Equal(t, "danger", attachment.Actions[0].Style)
+	assert.Equal(t, actionConfirm, attachment.Actions[0].Integration.Context["action"])
+	assert.Equal(t, confirmation.ID, attachment.Actions[0].Integration.Context["confirmation"])
+	assert.Equal(t, actionCancel, attachment.Actions[1].Integration.Context["action"])
+	assert.Equal(t, "https://mattermost.example.com/plugins/jenkins/action", attachment.Actions[1].Integration.URL)
+}
+
+func TestPendingConfirmationDescription(t *testing.T) {
+	assert.Equal(t, "delete the job 'folder/jobname'", (&pendingConfirmation{Command: "delete", Job: "folder/jobname"}).Description())
+	assert.Equal(t, "safe restart the Jenkins server", (&pendingConfirmation{Command: "safe-restart"}).Description())
+	assert.Equal(t, "safe restart the Jenkins instance 'ci'", (&pendingConfirmation{Command: "safe-restart", Instance: "ci"}).Description())
+}
+
+func TestHandleConfirmationAction(t *testing.T) {
+	restarted := false
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost && strings.TrimSuffix(req.URL.Path, "/") == "/safeRestart" {
+			restarted = true
+			return
+		}
+		res.WriteHeader(http.StatusNotFound)
+	}))
+	defer testServer.Close()
+
+	restart, err := json.Marshal(&pendingConfirmation{ID: "confirmation1", UserID: "user1", ChannelID: "channel1", Command: "safe-restart"})
+	assert.Nil(t, err)
+
+	for name, tc := range map[string]struct {
+		UserID            string
+		Action            string
+		Stored            []byte
+		Deleted           bool
+		ExpectedCode      int
+		ExpectedText      string
+		ExpectedRestarted bool
+	}{
+		"expired": {
+			UserID:       "user1",
+			Action:       actionConfirm,
+			ExpectedCode: http.StatusOK,
+			ExpectedText: "This confirmation has expired. Please run the command again.",
+		},
+		"already used": {
+			UserID:       "user1",
+			Action:       actionConfirm,
+			Stored:       restart,
+			ExpectedCode: http.StatusOK,
+			ExpectedText: "This confirmation has expired. Please run the command again.",
+		},
+		"other user": {
+			UserID:       "user2",
+			Action:       actionConfirm,
+			Stored:       restart,
+			Deleted:      true,
+			ExpectedCode: http.StatusUnauthorized,
+		},
+		"cancel": {
+			UserID:       "user1",
+			Action:       actionCancel,
+			Stored:       restart,
+			Deleted:      true,
+			ExpectedCode: http.StatusOK,
+			ExpectedText: "Cancelled, the command to safe restart the Jenkins server has not been run.",
+		},
+		"confirm": {
+			UserID:            "user1",
+			Action:            actionConfirm,
+			Stored:            restart,
+			Deleted:           true,
+			ExpectedCode:      http.StatusOK,
+			ExpectedRestarted: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			restarted = false
+			p, api := setupTestPlugin(t, testServer.URL)
+			api.On("KVGet", confirmationKey("confirmation1")).Return(tc.Stored, nil)
+			api.On("KVCompareAndDelete", confirmationKey("confirmation1"), tc.Stored).Return(tc.Deleted, nil)
+			api.On("DeleteEphemeralPost", tc.UserID, "post1").Return()
+			api.On("CreatePost", mock.Anything).Return(&model.Post{}, nil)
+
+			body := `{"user_id": "` + tc.UserID + `", "post_id": "post1", "context": {"action": "` + tc.Action + `", "confirmation": "confirmation1"}}`
+			r := httptest.NewRequest(http.MethodPost, "/action", strings.NewReader(body))
+			r.Header.Set("Mattermost-User-ID", tc.UserID)
+			w := httptest.NewRecorder()
+			p.handleAction(w, r)
+
+			assert.Equal(t, tc.ExpectedCode, w.Code)
+			assert.Equal(t, tc.ExpectedRestarted, restarted)
+			if tc.ExpectedCode != http.StatusOK {
+				api.AssertNotCalled(t, "KVCompareAndDelete", mock.Anything, mock.Anything)
+				return
+			}
+
+			var response model.PostActionIntegrationResponse
+			assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tc.ExpectedText, response.EphemeralText)
+			api.AssertCalled(t, "DeleteEphemeralPost", tc.UserID, "post1")
+			if tc.ExpectedRestarted {
+				api.AssertCalled(t, "CreatePost", mock.Anything)
+			}
+		})
+	}
+}
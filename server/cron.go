@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronMacros maps the supported cron macros to their expressions.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField is the range of the values of a field of a cron expression.
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	// Both 0 and 7 are Sunday.
+	{name: "day of week", min: 0, max: 7},
+}
+
+// cronSchedule is a parsed cron expression with the five standard fields:
+// minute, hour, day of month, month and day of week.
+type cronSchedule struct {
+	// fields holds a bit set of the allowed values of each field.
+	fields [5]uint64
+
+	// If both the day of month and the day of week are restricted, a day matches if any of them matches.
+	domRestricted bool
+	dowRestricted bool
+}
+
+// parseCron parses a cron expression. Each field accepts *, values, ranges like 1-5,
+// steps like */15 or 1-30/2 and comma separated lists of them. Macros like @daily are accepted too.
+func parseCron(expression string) (*cronSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	if macro, ok := cronMacros[strings.ToLower(expression)]; ok {
+		expression = macro
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(cronFields) {
+		return nil, errors.Errorf("expected %d fields, got %d", len(cronFields), len(parts))
+	}
+
+	schedule := &cronSchedule{}
+	for i, part := range parts {
+		bits, err := parseCronField(part, cronFields[i])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s", cronFields[i].name)
+		}
+		schedule.fields[i] = bits
+	}
+
+	// Sunday can be written either 0 or 7.
+	if schedule.fields[4]&(1<<7) != 0 {
+		schedule.fields[4] |= 1
+	}
+	schedule.domRestricted = !strings.HasPrefix(parts[2], "*")
+	schedule.dowRestricted = !strings.HasPrefix(parts[4], "*")
+
+	return schedule, nil
+}
+
+// parseCronField parses a field of a cron expression into a bit set of its allowed values.
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			rangePart = item[:i]
+			var err error
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step < 1 {
+				return 0, errors.Errorf("invalid step in '%s'", item)
+			}
+		}
+
+		start, end := bounds.min, bounds.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			values := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(values[0]); err != nil {
+				return 0, errors.Errorf("invalid value in '%s'", item)
+			}
+			if end, err = strconv.Atoi(values[1]); err != nil {
+				return 0, errors.Errorf("invalid value in '%s'", item)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, errors.Errorf("invalid value in '%s'", item)
+			}
+			start = value
+			// A single value with a step, like 5/15, runs from the value to the end of the range.
+			if step == 1 {
+				end = value
+			}
+		}
+
+		if start < bounds.min || end > bounds.max || start > end {
+			return 0, errors.Errorf("'%s' is out of the range %d-%d", item, bounds.min, bounds.max)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+
+	return bits, nil
+}
+
+// Matches checks if the schedule fires at the minute of the given time.
+func (s *cronSchedule) Matches(t time.Time) bool {
+	if s.fields[0]&(1<<uint(t.Minute())) == 0 ||
+		s.fields[1]&(1<<uint(t.Hour())) == 0 ||
+		s.fields[3]&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatches := s.fields[2]&(1<<uint(t.Day())) != 0
+	dowMatches := s.fields[4]&(1<<uint(t.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domMatches || dowMatches
+	}
+	return domMatches && dowMatches
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Expression string
+		Matching   []string
+		Missing    []string
+		Invalid    bool
+	}{
+		"every minute": {
+			Expression: "* * * * *",
+			Matching:   []string{"2024-03-04 10:17"},
+		},
+		"nightly": {
+			Expression: "0 2 * * *",
+			Matching:   []string{"2024-03-04 02:00"},
+			Missing:    []string{"2024-03-04 02:01", "2024-03-04 03:00"},
+		},
+		"steps, ranges and lists": {
+			Expression: "*/15 8-18/2 * 1,6 *",
+			Matching:   []string{"2024-01-04 08:45", "2024-06-04 18:00"},
+			Missing:    []string{"2024-01-04 09:00", "2024-01-04 08:10", "2024-02-04 08:00"},
+		},
+		"week days": {
+			Expression: "30 9 * * 1-5",
+			Matching:   []string{"2024-03-04 09:30"},
+			Missing:    []string{"2024-03-03 09:30", "2024-03-09 09:30"},
+		},
+		"sunday as 7": {
+			Expression: "0 0 * * 7",
+			Matching:   []string{"2024-03-03 00:00"},
+		},
+		"day of month or day of week": {
+			Expression: "0 0 1 * 1",
+			Matching:   []string{"2024-03-01 00:00", "2024-03-04 00:00"},
+			Missing:    []string{"2024-03-05 00:00"},
+		},
+		"macro": {
+			Expression: "@daily",
+			Matching:   []string{"2024-03-05 00:00"},
+			Missing:    []string{"2024-03-05 01:00"},
+		},
+		"too few fields":    {Expression: "0 2 * *", Invalid: true},
+		"out of range":      {Expression: "60 * * * *", Invalid: true},
+		"reversed range":    {Expression: "* 5-2 * * *", Invalid: true},
+		"invalid step":      {Expression: "*/0 * * * *", Invalid: true},
+		"not a number":      {Expression: "a * * * *", Invalid: true},
+		"zero day of month": {Expression: "* * 0 * *", Invalid: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			schedule, err := parseCron(tc.Expression)
+			if tc.Invalid {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+
+			for _, value := range tc.Matching {
+				date, _ := time.Parse("2006-01-02 15:04", value)
+				assert.True(t, schedule.Matches(date), value)
+			}
+			for _, value := range tc.Missing {
+				date, _ := time.Parse("2006-01-02 15:04", value)
+				assert.False(t, schedule.Matches(date), value)
+			}
+		})
+	}
+}
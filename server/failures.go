@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// failureContextLines is the number of lines displayed around each error marker.
+	failureContextLines = 2
+
+	// maxFailureExcerptLines limits the size of an excerpt. The last lines are kept, as the
+	// errors which failed the build are usually at the end of the console output.
+	maxFailureExcerptLines = 30
+
+	// maxFailureExcerptLineLength truncates long lines of the excerpt.
+	maxFailureExcerptLineLength = 300
+)
+
+// extractFailureExcerpt returns the lines of the console output matching any of the patterns,
+// with contextLines lines of context around them. Gaps between the matches are marked with "...".
+// Returns an empty string if no line matches.
+func extractFailureExcerpt(consoleOutput string, patterns []*regexp.Regexp, contextLines, maxLines int) string {
+	lines := strings.Split(strings.TrimRight(consoleOutput, "\n"), "\n")
+
+	selected := make([]bool, len(lines))
+	for i, line := range lines {
+		for _, pattern := range patterns {
+			if !pattern.MatchString(line) {
+				continue
+			}
+
+			for j := i - contextLines; j <= i+contextLines; j++ {
+				if j >= 0 && j < len(lines) {
+					selected[j] = true
+				}
+			}
+			break
+		}
+	}
+
+	indexes := []int{}
+	for i, ok := range selected {
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	if len(indexes) == 0 {
+		return ""
+	}
+	if len(indexes) > maxLines {
+		indexes = indexes[len(indexes)-maxLines:]
+	}
+
+	excerpt := []string{}
+	for n, i := range indexes {
+		if (n == 0 && i > 0) || (n > 0 && i != indexes[n-1]+1) {
+			excerpt = append(excerpt, "...")
+		}
+
+		line := lines[i]
+		if runes := []rune(line); len(runes) > maxFailureExcerptLineLength {
+			line = string(runes[:maxFailureExcerptLineLength]) + "..."
+		}
+		excerpt = append(excerpt, line)
+	}
+	if indexes[len(indexes)-1] < len(lines)-1 {
+		excerpt = append(excerpt, "...")
+	}
+
+	return strings.Join(excerpt, "\n")
+}
+
+// getFailurePatterns returns the configured failure patterns.
+// The default patterns are used if any of the configured ones is invalid, so that a typo doesn't disable the plugin.
+func (p *Plugin) getFailurePatterns() []*regexp.Regexp {
+	patterns, err := p.getConfiguration().getFailurePatterns()
+	if err != nil {
+		p.API.LogWarn("Invalid failure patterns, using the default ones", "err", err.Error())
+		patterns, _ = (&configuration{}).getFailurePatterns()
+	}
+	return patterns
+}
+
+// getConsoleOutput fetches the whole console output of a build.
+// Unlike build.GetConsoleOutput, it reports the errors instead of returning an empty output.
+func getConsoleOutput(build *gojenkins.Build) (string, error) {
+	var content string
+	resp, err := build.Jenkins.Requester.GetXML(build.Base+"/consoleText", &content, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "Error fetching the console output")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unexpected status code %d while fetching the console output", resp.StatusCode)
+	}
+
+	return content, nil
+}
+
+// postFailureExcerpt posts the lines of the console output of a build matching the failure patterns.
+// Returns false if the console output has no error markers, in which case nothing is posted.
+func (p *Plugin) postFailureExcerpt(userID, channelID, jobName string, build *gojenkins.Build) (bool, error) {
+	consoleOutput, err := getConsoleOutput(build)
+	if err != nil {
+		return false, err
+	}
+
+	excerpt := extractFailureExcerpt(consoleOutput, p.getFailurePatterns(), failureContextLines, maxFailureExcerptLines)
+	if excerpt == "" {
+		return false, nil
+	}
+
+	attachment := generateSlackAttachment(fmt.Sprintf("Failure excerpt of the build #%d of the job '%s'\n%s\n[Full console output](%sconsole)", build.GetBuildNumber(), jobName, formatLogChunk(excerpt), build.GetUrl()))
+	attachment.Color = buildResultColor(build.GetResult())
+	if post := p.createAttachmentPost(userID, channelID, jobInstance(jobName), attachment); post == nil {
+		return false, errors.New("failed to create the failure excerpt post")
+	}
+
+	return true, nil
+}
+
+func (p *Plugin) executeWhyFailedCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	jobName, buildNumber, _, ok := parseBuildParameters(parameters)
+	if !ok {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to find why a build failed.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	build, err := p.getBuild(jobName, args.UserId, buildNumber)
+	if err != nil {
+		p.API.LogError("Error fetching the build", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while fetching the build.")
+	}
+
+	posted, err := p.postFailureExcerpt(args.UserId, args.ChannelId, jobName, build)
+	if err != nil {
+		p.API.LogError("Error posting the failure excerpt", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while looking for errors in the console output.")
+	}
+
+	if !posted {
+		return p.getCommandResponse(args, fmt.Sprintf("No error markers found in the console output of the build #%d of the job '%s'.", build.GetBuildNumber(), jobName))
+	}
+
+	return &model.CommandResponse{}
+}
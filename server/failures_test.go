@@ -0,0 +1,106 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/waseem18/gojenkins"
+)
+
+func TestExtractFailureExcerpt(t *testing.T) {
+	patterns := []*regexp.Regexp{regexp.MustCompile("ERROR"), regexp.MustCompile("exit code")}
+	consoleOutput := "line1\nline2\nline3\nERROR: compilation failed\nline5\nline6\nline7\nline8\nline9\nscript returned exit code 1\n"
+
+	for name, tc := range map[string]struct {
+		ContextLines int
+		MaxLines     int
+		Expected     string
+	}{
+		"with context": {
+			ContextLines: 1,
+			MaxLines:     30,
+			Expected:     "...\nline3\nERROR: compilation failed\nline5\n...\nline9\nscript returned exit code 1",
+		},
+		"overlapping context is merged": {
+			ContextLines: 3,
+			MaxLines:     30,
+			Expected:     "line1\nline2\nline3\nERROR: compilation failed\nline5\nline6\nline7\nline8\nline9\nscript returned exit code 1",
+		},
+		"keeps the last lines": {
+			ContextLines: 1,
+			MaxLines:     3,
+			Expected:     "...\nline5\n...\nline9\nscript returned exit code 1",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, extractFailureExcerpt(consoleOutput, patterns, tc.ContextLines, tc.MaxLines))
+		})
+	}
+
+	assert.Equal(t, "", extractFailureExcerpt("all good\n", patterns, 2, 30))
+}
+
+func TestPluginGetFailurePatterns(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	api.On("LogWarn", "Invalid failure patterns, using the default ones", "err", mock.Anything).Return()
+
+	p.setConfiguration(&configuration{FailurePatterns: "^FATAL"}, &model.Config{})
+	assert.Len(t, p.getFailurePatterns(), 1)
+	api.AssertNotCalled(t, "LogWarn", mock.Anything, mock.Anything, mock.Anything)
+
+	config := &configuration{JenkinsURL: "https://jenkins.example.com", FailurePatterns: "[unclosed"}
+	assert.Nil(t, p.IsValid(config), "an invalid failure pattern must not prevent the activation")
+
+	p.setConfiguration(config, &model.Config{})
+	assert.Len(t, p.getFailurePatterns(), len(defaultFailurePatterns))
+	api.AssertCalled(t, "LogWarn", "Invalid failure patterns, using the default ones", "err", mock.Anything)
+}
+
+func TestPostFailureExcerpt(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch strings.TrimSuffix(req.URL.Path, "/") {
+		case "/job/jobname/42/consoleText":
+			_, _ = res.Write([]byte("Building\nERROR: compilation failed\nFinished: FAILURE\n"))
+		case "/job/jobname/43/consoleText":
+			_, _ = res.Write([]byte("Building\nFinished: FAILURE\n"))
+		default:
+			res.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer testServer.Close()
+
+	p, api := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	var post *model.Post
+	api.On("CreatePost", mock.Anything).Run(func(args mock.Arguments) {
+		post = args.Get(0).(*model.Post)
+	}).Return(&model.Post{}, nil)
+
+	newBuild := func(base string) *gojenkins.Build {
+		return &gojenkins.Build{Jenkins: jenkins, Base: base, Raw: &gojenkins.BuildResponse{Number: 42, Result: "FAILURE"}}
+	}
+
+	posted, err := p.postFailureExcerpt("user1", "channel1", "jobname", newBuild("/job/jobname/42"))
+	assert.Nil(t, err)
+	assert.True(t, posted)
+	assert.Contains(t, post.Props["attachments"].([]*model.SlackAttachment)[0].Text, "ERROR: compilation failed")
+
+	posted, err = p.postFailureExcerpt("user1", "channel1", "jobname", newBuild("/job/jobname/43"))
+	assert.Nil(t, err)
+	assert.False(t, posted)
+
+	posted, err = p.postFailureExcerpt("user1", "channel1", "jobname", newBuild("/job/secret/1"))
+	assert.NotNil(t, err)
+	assert.False(t, posted)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultFlakyBuilds = 10
+	maxFlakyBuilds     = 50
+
+	// maxListedFlakyTests limits the number of tests listed in a flaky test report.
+	maxListedFlakyTests = 20
+)
+
+// flakyTest is a test which flipped between passing and failing across builds.
+type flakyTest struct {
+	Name     string
+	Runs     int
+	Failures int
+	Flips    int
+}
+
+// FlipRate returns the ratio of runs where the outcome of the test changed compared to the previous run.
+func (t *flakyTest) FlipRate() float64 {
+	if t.Runs < 2 {
+		return 0
+	}
+	return float64(t.Flips) / float64(t.Runs-1)
+}
+
+// findFlakyTests returns the tests which flipped between passing and failing across the reports,
+// sorted by flip rate. Reports are expected from the oldest build to the newest, nil reports are ignored.
+func findFlakyTests(reports []*testReport) []flakyTest {
+	tests := map[string]*flakyTest{}
+	lastFailed := map[string]bool{}
+
+	for _, report := range reports {
+		if report == nil {
+			continue
+		}
+
+		for _, c := range report.Cases() {
+			if !c.Failed() && !c.Passed() {
+				continue
+			}
+
+			name := c.FullName()
+			test, ok := tests[name]
+			if !ok {
+				test = &flakyTest{Name: name}
+				tests[name] = test
+			} else if lastFailed[name] != c.Failed() {
+				test.Flips++
+			}
+
+			test.Runs++
+			if c.Failed() {
+				test.Failures++
+			}
+			lastFailed[name] = c.Failed()
+		}
+	}
+
+	flaky := []flakyTest{}
+	for _, test := range tests {
+		if test.Flips > 0 {
+			flaky = append(flaky, *test)
+		}
+	}
+
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].FlipRate() != flaky[j].FlipRate() {
+			return flaky[i].FlipRate() > flaky[j].FlipRate()
+		}
+		return flaky[i].Name < flaky[j].Name
+	})
+
+	return flaky
+}
+
+// postFlakyTests posts the tests which flipped between passing and failing in the last builds of the job.
+func (p *Plugin) postFlakyTests(userID, channelID, jobName string, builds int) error {
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return err
+	}
+
+	jobBuilds := job.Raw.Builds
+	if len(jobBuilds) > builds {
+		jobBuilds = jobBuilds[:builds]
+	}
+
+	instance := jobInstance(jobName)
+	if len(jobBuilds) == 0 {
+		p.createPost(userID, channelID, instance, fmt.Sprintf("The job '%s' has no builds.", jobName))
+		return nil
+	}
+
+	// Builds are listed from the newest to the oldest.
+	reports := []*testReport{}
+	for i := len(jobBuilds) - 1; i >= 0; i-- {
+		report, err := getTestReport(job.Jenkins, job.Base+"/"+strconv.FormatInt(jobBuilds[i].Number, 10))
+		if err != nil {
+			return errors.Wrapf(err, "Error fetching the test report of the build #%d", jobBuilds[i].Number)
+		}
+		reports = append(reports, report)
+	}
+
+	msg := fmt.Sprintf("Flaky tests of the job '%s' over the last %d build(s), #%d to #%d\n\n", jobName, len(jobBuilds), jobBuilds[len(jobBuilds)-1].Number, jobBuilds[0].Number)
+	flaky := findFlakyTests(reports)
+	if len(flaky) == 0 {
+		p.createPost(userID, channelID, instance, msg+"No test flipped between passing and failing.")
+		return nil
+	}
+
+	msg += "| Test | Flip rate | Flips | Failures | Runs |\n|:--|:--|:--|:--|:--|\n"
+	for i, test := range flaky {
+		if i == maxListedFlakyTests {
+			msg += fmt.Sprintf("\nand %d more.", len(flaky)-maxListedFlakyTests)
+			break
+		}
+		msg += fmt.Sprintf("| %s | %.0f%% | %d | %d | %d |\n", escapeTableCell(test.Name), test.FlipRate()*100, test.Flips, test.Failures, test.Runs)
+	}
+
+	p.createPost(userID, channelID, instance, msg)
+	return nil
+}
+
+func (p *Plugin) executeFlakyCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	parameters, buildsValue, ok := extractFlag(parameters, "builds")
+	if !ok {
+		return p.getCommandResponse(args, "Please specify a number of builds after `--builds`.")
+	}
+
+	builds := defaultFlakyBuilds
+	if buildsValue != "" {
+		var err error
+		builds, err = strconv.Atoi(buildsValue)
+		if err != nil || builds < 2 || builds > maxFlakyBuilds {
+			return p.getCommandResponse(args, fmt.Sprintf("The number of builds must be between 2 and %d.", maxFlakyBuilds))
+		}
+	}
+
+	jobName, rest, ok := splitJobName(parameters)
+	if !ok || jobName == "" || len(rest) != 0 {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to find flaky tests.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	// Fetching the test reports of many builds takes a while.
+	go func() {
+		if err := p.postFlakyTests(args.UserId, args.ChannelId, jobName, builds); err != nil {
+			p.API.LogError("Error looking for flaky tests", "job_name", jobName, "err", err.Error())
+			p.createEphemeralPost(args.UserId, args.ChannelId, fmt.Sprintf("Encountered an error while looking for flaky tests of the job '%s'.", jobName))
+		}
+	}()
+
+	return p.getCommandResponse(args, fmt.Sprintf("Looking for flaky tests in the last %d builds of the job '%s'...", builds, jobName))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindFlakyTests(t *testing.T) {
+	passed := func(name string) testCase { return testCase{ClassName: "pkg.A", Name: name, Status: "PASSED"} }
+	failed := func(name string) testCase { return testCase{ClassName: "pkg.A", Name: name, Status: "FAILED"} }
+	skipped := func(name string) testCase { return testCase{ClassName: "pkg.A", Name: name, Status: "SKIPPED"} }
+
+	reports := []*testReport{
+		newTestReport(passed("flaky"), failed("broken"), passed("stable"), passed("flippedOnce")),
+		newTestReport(failed("flaky"), failed("broken"), passed("stable"), passed("flippedOnce")),
+		nil,
+		newTestReport(passed("flaky"), failed("broken"), passed("stable"), skipped("flippedOnce")),
+		newTestReport(failed("flaky"), failed("broken"), passed("stable"), failed("flippedOnce")),
+	}
+
+	flaky := findFlakyTests(reports)
+	assert.Equal(t, []flakyTest{
+		{Name: "pkg.A.flaky", Runs: 4, Failures: 2, Flips: 3},
+		{Name: "pkg.A.flippedOnce", Runs: 3, Failures: 1, Flips: 1},
+	}, flaky)
+	assert.Equal(t, 1.0, flaky[0].FlipRate())
+	assert.Equal(t, 0.5, flaky[1].FlipRate())
+
+	assert.Empty(t, findFlakyTests(nil))
+}
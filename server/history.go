@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	defaultHistoryBuilds = 10
+	maxHistoryBuilds     = 50
+
+	// maxParametersSummaryLength truncates the summary of the parameters of a build in the history table.
+	maxParametersSummaryLength = 60
+)
+
+// historyBuild is a build of a job, as returned by the tree query of getBuildHistory.
+type historyBuild struct {
+	Number    int64  `json:"number"`
+	URL       string `json:"url"`
+	Result    string `json:"result"`
+	Building  bool   `json:"building"`
+	Duration  int64  `json:"duration"`
+	Timestamp int64  `json:"timestamp"`
+	Actions   []struct {
+		Causes []struct {
+			ShortDescription string `json:"shortDescription"`
+		} `json:"causes"`
+		Parameters []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"parameters"`
+	} `json:"actions"`
+}
+
+// Cause returns the description of the first cause of the build.
+func (b *historyBuild) Cause() string {
+	for _, action := range b.Actions {
+		for _, cause := range action.Causes {
+			if cause.ShortDescription != "" {
+				return cause.ShortDescription
+			}
+		}
+	}
+	return ""
+}
+
+// ParametersSummary returns the parameters of the build as a list of key=value sorted by name,
+// truncated to maxParametersSummaryLength.
+func (b *historyBuild) ParametersSummary() string {
+	parameters := []string{}
+	for _, action := range b.Actions {
+		for _, parameter := range action.Parameters {
+			if value, ok := parameterValueString(parameter.Value); ok {
+				parameters = append(parameters, parameter.Name+"="+value)
+			}
+		}
+	}
+	sort.Strings(parameters)
+
+	summary := strings.Join(parameters, ", ")
+	if runes := []rune(summary); len(runes) > maxParametersSummaryLength {
+		summary = string(runes[:maxParametersSummaryLength]) + "..."
+	}
+	return summary
+}
+
+// getBuildHistory fetches the last builds of the job, from the newest to the oldest.
+func getBuildHistory(job *gojenkins.Job, count int) ([]historyBuild, error) {
+	response := struct {
+		Builds []historyBuild `json:"builds"`
+	}{}
+	query := map[string]string{
+		"tree": fmt.Sprintf("builds[number,url,result,building,duration,timestamp,actions[causes[shortDescription],parameters[name,value]]]{0,%d}", count),
+	}
+	resp, err := job.Jenkins.Requester.GetJSON(job.Base, &response, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the build history")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching the build history", resp.StatusCode)
+	}
+
+	return response.Builds, nil
+}
+
+// buildHistoryTable renders the builds of a job as a table.
+func buildHistoryTable(jobName string, builds []historyBuild) string {
+	msg := fmt.Sprintf("Last %d build(s) of the job '%s'\n\n", len(builds), jobName)
+	msg += "| Build | Result | Duration | Started | Cause | Parameters |\n|:--|:--|:--|:--|:--|:--|\n"
+	for _, build := range builds {
+		result := build.Result
+		duration := formatDuration(build.Duration)
+		if build.Building {
+			result = "BUILDING"
+			duration = "-"
+		}
+
+		started := time.Unix(0, build.Timestamp*int64(time.Millisecond)).UTC().Format("2006-01-02 15:04 MST")
+		msg += fmt.Sprintf("| [#%d](%s) | %s | %s | %s | %s | %s |\n", build.Number, build.URL, result, duration, started, escapeTableCell(build.Cause()), escapeTableCell(build.ParametersSummary()))
+	}
+	return msg
+}
+
+// postBuildHistory posts a table of the last builds of the job.
+func (p *Plugin) postBuildHistory(userID, channelID, jobName string, count int) error {
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return err
+	}
+
+	builds, err := getBuildHistory(job, count)
+	if err != nil {
+		return err
+	}
+
+	instance := jobInstance(jobName)
+	if len(builds) == 0 {
+		p.createPost(userID, channelID, instance, fmt.Sprintf("The job '%s' has no builds.", jobName))
+		return nil
+	}
+
+	p.createPost(userID, channelID, instance, buildHistoryTable(jobName, builds))
+	return nil
+}
+
+func (p *Plugin) executeHistoryCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	jobName, rest, ok := splitJobName(parameters)
+	if !ok || jobName == "" || len(rest) > 1 {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get the build history of a job.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	count := defaultHistoryBuilds
+	if len(rest) == 1 {
+		var err error
+		count, err = strconv.Atoi(rest[0])
+		if err != nil || count < 1 || count > maxHistoryBuilds {
+			return p.getCommandResponse(args, fmt.Sprintf("The number of builds must be between 1 and %d.", maxHistoryBuilds))
+		}
+	}
+
+	if err := p.postBuildHistory(args.UserId, args.ChannelId, jobName, count); err != nil {
+		p.API.LogError("Error fetching the build history", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, fmt.Sprintf("Encountered an error while fetching the build history of the job '%s'.", jobName))
+	}
+
+	return &model.CommandResponse{}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBuildHistory(t *testing.T) {
+	var tree string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, "/job/jobname/api/json") && req.URL.Query().Get("tree") != "" {
+			tree = req.URL.Query().Get("tree")
+			_, _ = res.Write([]byte(`{"builds": [
+				{"number": 42, "url": "http://jenkins/job/jobname/42/", "building": true, "timestamp": 1700000000000},
+				{"number": 41, "url": "http://jenkins/job/jobname/41/", "result": "FAILURE", "duration": 61000, "timestamp": 1699990000000,
+					"actions": [{"causes": [{"shortDescription": "Started by user admin"}]}, {"parameters": [{"name": "target", "value": "eu"}, {"name": "dry_run", "value": false}]}]}]}`))
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	job, err := p.getJob("user1", "jobname")
+	assert.Nil(t, err)
+
+	builds, err := getBuildHistory(job, 2)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(tree, "{0,2}"))
+	assert.Len(t, builds, 2)
+	assert.Equal(t, "", builds[0].Cause())
+	assert.Equal(t, "Started by user admin", builds[1].Cause())
+	assert.Equal(t, "dry_run=false, target=eu", builds[1].ParametersSummary())
+
+	table := buildHistoryTable("jobname", builds)
+	assert.Contains(t, table, "Last 2 build(s) of the job 'jobname'")
+	assert.Contains(t, table, "| [#42](http://jenkins/job/jobname/42/) | BUILDING | - | 2023-11-14 22:13 UTC |  |  |\n")
+	assert.Contains(t, table, "| [#41](http://jenkins/job/jobname/41/) | FAILURE | 1m1s | 2023-11-14 19:26 UTC | Started by user admin | dry_run=false, target=eu |\n")
+}
+
+func TestParametersSummary(t *testing.T) {
+	var build historyBuild
+	err := json.Unmarshal([]byte(`{"actions": [{"parameters": [{"name": "message", "value": "`+strings.Repeat("a", 100)+`"}]}]}`), &build)
+	assert.Nil(t, err)
+
+	summary := build.ParametersSummary()
+	assert.Equal(t, maxParametersSummaryLength+len("..."), len(summary))
+	assert.True(t, strings.HasPrefix(summary, "message=aaa"))
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	actionInputProceed = "input-proceed"
+	actionInputAbort   = "input-abort"
+)
+
+// errNotAPipeline is returned when the pending inputs of a build which is not a Pipeline run are requested.
+var errNotAPipeline = errors.New("the build is not a Pipeline run or the Pipeline Stage View plugin is not installed")
+
+// pipelineInput is an input step a Pipeline run is paused on, as returned by the wfapi/pendingInputActions API.
+type pipelineInput struct {
+	ID          string                   `json:"id"`
+	Message     string                   `json:"message"`
+	ProceedText string                   `json:"proceedText"`
+	Inputs      []pipelineInputParameter `json:"inputs"`
+}
+
+type pipelineInputParameter struct {
+	Type        string                 `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Definition  jobParameterDefinition `json:"definition"`
+}
+
+// ParameterDefinitions returns the definitions of the parameters the input asks for.
+func (i *pipelineInput) ParameterDefinitions() []jobParameterDefinition {
+	definitions := []jobParameterDefinition{}
+	for _, input := range i.Inputs {
+		definition := input.Definition
+		if definition.Name == "" {
+			definition.Name = input.Name
+		}
+		if definition.Type == "" {
+			definition.Type = input.Type
+		}
+		if definition.Description == "" {
+			definition.Description = input.Description
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions
+}
+
+// inputDialogState is the state of the dialog asking for the parameters of an input step.
+type inputDialogState struct {
+	Job   string `json:"job"`
+	Build string `json:"build"`
+	Input string `json:"input"`
+}
+
+// getPendingInputs fetches the input steps the Pipeline build is paused on.
+func getPendingInputs(build *gojenkins.Build) ([]pipelineInput, error) {
+	inputs := []pipelineInput{}
+	response, err := build.Jenkins.Requester.Get(build.Base+"/wfapi/pendingInputActions", &inputs, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the pending inputs")
+	}
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, errNotAPipeline
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching the pending inputs", response.StatusCode)
+	}
+
+	return inputs, nil
+}
+
+// getPendingInput fetches the input step of the build with the given ID.
+// Returns nil if the build is not paused on it anymore.
+func getPendingInput(build *gojenkins.Build, inputID string) (*pipelineInput, error) {
+	inputs, err := getPendingInputs(build)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range inputs {
+		if inputs[i].ID == inputID {
+			return &inputs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// submitInput proceeds the input step with the given parameters, or aborts it.
+func submitInput(build *gojenkins.Build, input *pipelineInput, proceed bool, parameters map[string]string) error {
+	endpoint := build.Base + "/input/" + url.PathEscape(input.ID) + "/abort"
+	var payload io.Reader
+	var query map[string]string
+	if proceed && len(input.Inputs) == 0 {
+		endpoint = build.Base + "/input/" + url.PathEscape(input.ID) + "/proceedEmpty"
+	} else if proceed {
+		endpoint = build.Base + "/wfapi/inputSubmit"
+		query = map[string]string{"inputId": input.ID}
+
+		values := []map[string]string{}
+		for _, definition := range input.ParameterDefinitions() {
+			if value, ok := parameters[definition.Name]; ok {
+				values = append(values, map[string]string{"name": definition.Name, "value": value})
+			}
+		}
+		body, err := json.Marshal(map[string]interface{}{"parameter": values})
+		if err != nil {
+			return err
+		}
+		payload = strings.NewReader(url.Values{"json": {string(body)}}.Encode())
+	}
+
+	response, err := build.Jenkins.Requester.Post(endpoint, payload, nil, query)
+	if err != nil {
+		return errors.Wrap(err, "Error submitting the input")
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("unexpected status code %d while submitting the input", response.StatusCode)
+	}
+
+	return nil
+}
+
+// inputAttachment creates an attachment with the prompt of an input step and buttons to proceed or abort it.
+func (p *Plugin) inputAttachment(jobName string, build *gojenkins.Build, input *pipelineInput) *model.SlackAttachment {
+	text := fmt.Sprintf("Job '%s' - #%d is waiting for input\nBuild URL : %s\n\n%s", jobName, build.GetBuildNumber(), build.GetUrl(), input.Message)
+	if len(input.Inputs) > 0 {
+		names := []string{}
+		for _, definition := range input.ParameterDefinitions() {
+			names = append(names, "`"+definition.Name+"`")
+		}
+		text += "\n\nParameters: " + strings.Join(names, ", ")
+	}
+
+	proceedText := input.ProceedText
+	if proceedText == "" {
+		proceedText = "Proceed"
+	}
+
+	proceed := p.buildAction(proceedText, actionInputProceed, jobName, build.GetBuildNumber())
+	proceed.Integration.Context["input"] = input.ID
+	proceed.Style = "primary"
+	abort := p.buildAction("Abort", actionInputAbort, jobName, build.GetBuildNumber())
+	abort.Integration.Context["input"] = input.ID
+	abort.Style = "danger"
+
+	attachment := generateSlackAttachment(text)
+	attachment.Color = buildResultColor("UNSTABLE")
+	attachment.Actions = []*model.PostAction{proceed, abort}
+	return attachment
+}
+
+// postNewPendingInputs posts the prompts of the input steps the build is paused on,
+// skipping the ones already in posted. The IDs of the posted inputs are added to posted.
+func (p *Plugin) postNewPendingInputs(userID, channelID, jobName string, build *gojenkins.Build, posted map[string]bool) error {
+	inputs, err := getPendingInputs(build)
+	if err != nil {
+		return err
+	}
+
+	for i := range inputs {
+		if posted[inputs[i].ID] {
+			continue
+		}
+		p.createAttachmentPost(userID, channelID, jobInstance(jobName), p.inputAttachment(jobName, build, &inputs[i]))
+		posted[inputs[i].ID] = true
+	}
+	return nil
+}
+
+// runInputAction proceeds or aborts an input step of a build, with the credentials of the user.
+// If the input asks for parameters, a dialog is opened for the user to fill them in instead of proceeding right away.
+// Returns the message to display to the user.
+func (p *Plugin) runInputAction(userID, channelID, triggerID, action, jobName, buildID, inputID string) (string, error) {
+	build, err := p.getBuild(jobName, userID, buildID)
+	if err != nil {
+		return "", err
+	}
+
+	input, err := getPendingInput(build, inputID)
+	if err != nil {
+		return "", err
+	}
+	if input == nil {
+		return fmt.Sprintf("Build #%s of the job '%s' is not waiting for this input anymore.", buildID, jobName), nil
+	}
+
+	if action == actionInputProceed && len(input.Inputs) > 0 {
+		if err := p.openInputDialog(userID, triggerID, jobName, buildID, input); err != nil {
+			return "", err
+		}
+		return "", nil
+	}
+
+	if err := submitInput(build, input, action == actionInputProceed, nil); err != nil {
+		return "", err
+	}
+
+	p.postInputSubmitted(userID, channelID, jobName, buildID, action == actionInputProceed)
+	return "", nil
+}
+
+// postInputSubmitted posts who proceeded or aborted an input step of a build.
+func (p *Plugin) postInputSubmitted(userID, channelID, jobName, buildID string, proceed bool) {
+	username := userID
+	if user, appErr := p.API.GetUser(userID); appErr == nil {
+		username = user.Username
+	}
+
+	verb := "aborted"
+	if proceed {
+		verb = "approved"
+	}
+	p.createPost(userID, channelID, jobInstance(jobName), fmt.Sprintf("Input of the build #%s of the job '%s' has been %s by @%s.", buildID, jobName, verb, username))
+}
+
+// openInputDialog opens an interactive dialog for the user to fill in the parameters of an input step.
+func (p *Plugin) openInputDialog(userID, triggerID, jobName, buildID string, input *pipelineInput) error {
+	state, err := json.Marshal(inputDialogState{Job: jobName, Build: buildID, Input: input.ID})
+	if err != nil {
+		return err
+	}
+
+	elements := []model.DialogElement{}
+	for _, definition := range input.ParameterDefinitions() {
+		elements = append(elements, dialogElementForParameter(definition))
+	}
+
+	submitLabel := input.ProceedText
+	if submitLabel == "" {
+		submitLabel = "Proceed"
+	}
+
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("%s/plugins/jenkins/input", siteURL),
+		Dialog: model.Dialog{
+			Title:            fmt.Sprintf("Input of %s #%s", jobName, buildID),
+			IntroductionText: input.Message,
+			CallbackId:       userID,
+			SubmitLabel:      submitLabel,
+			Elements:         elements,
+			State:            string(state),
+		},
+	}
+	if appErr := p.API.OpenInteractiveDialog(dialog); appErr != nil {
+		return errors.Wrap(appErr, "Error opening the input dialog")
+	}
+	return nil
+}
+
+// handleInputSubmission proceeds an input step with the parameters submitted in its dialog.
+func (p *Plugin) handleInputSubmission(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var state inputDialogState
+	if err := json.Unmarshal([]byte(request.State), &state); err != nil || state.Job == "" || state.Input == "" {
+		http.Error(w, "Invalid dialog state", http.StatusBadRequest)
+		return
+	}
+
+	if allowed, denial := p.checkCommandPermission(userID, request.ChannelId, "input"); !allowed {
+		writeDialogError(w, denial)
+		return
+	}
+
+	if err := p.submitInputParameters(userID, request.ChannelId, state, dialogSubmissionToParameters(request.Submission)); err != nil {
+		p.API.LogError("Error submitting the input", "job_name", state.Job, "build", state.Build, "err", err.Error())
+		p.createEphemeralPost(userID, request.ChannelId, fmt.Sprintf("Encountered an error while submitting the input of the build #%s of the job '%s'.", state.Build, state.Job))
+	}
+}
+
+func (p *Plugin) submitInputParameters(userID, channelID string, state inputDialogState, parameters map[string]string) error {
+	build, err := p.getBuild(state.Job, userID, state.Build)
+	if err != nil {
+		return err
+	}
+
+	input, err := getPendingInput(build, state.Input)
+	if err != nil {
+		return err
+	}
+	if input == nil {
+		p.createEphemeralPost(userID, channelID, fmt.Sprintf("Build #%s of the job '%s' is not waiting for this input anymore.", state.Build, state.Job))
+		return nil
+	}
+
+	if err := submitInput(build, input, true, parameters); err != nil {
+		return err
+	}
+
+	p.postInputSubmitted(userID, channelID, state.Job, state.Build, true)
+	return nil
+}
+
+// postPendingInputs posts the prompts of all the input steps a build is paused on.
+// The last build of the job is used if buildID is an empty string.
+func (p *Plugin) postPendingInputs(userID, channelID, jobName, buildID string) (int, error) {
+	build, err := p.getBuild(jobName, userID, buildID)
+	if err != nil {
+		return 0, err
+	}
+
+	posted := map[string]bool{}
+	if err := p.postNewPendingInputs(userID, channelID, jobName, build, posted); err != nil {
+		return 0, err
+	}
+	return len(posted), nil
+}
+
+func (p *Plugin) executeInputCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	jobName, buildNumber, _, ok := parseBuildParameters(parameters)
+	if !ok {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to answer the input steps of a build.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	count, err := p.postPendingInputs(args.UserId, args.ChannelId, jobName, buildNumber)
+	if err != nil {
+		p.API.LogError("Error fetching the pending inputs", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while fetching the pending inputs of the build. Make sure the job is a Pipeline.")
+	}
+
+	if count == 0 {
+		return p.getCommandResponse(args, fmt.Sprintf("The build of the job '%s' is not waiting for any input.", jobName))
+	}
+	return &model.CommandResponse{}
+}
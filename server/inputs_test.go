@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waseem18/gojenkins"
+)
+
+func TestGetPendingInputs(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.TrimSuffix(req.URL.Path, "/") == "/job/jobname/42/wfapi/pendingInputActions" {
+			_, _ = res.Write([]byte(`[{"id": "Deploy", "proceedText": "Deploy", "message": "Deploy to production?",
+				"inputs": [{"type": "ChoiceParameterDefinition", "name": "target", "description": "Where to deploy",
+					"definition": {"choices": ["eu", "us"], "defaultParameterValue": {"value": "eu"}}}],
+				"proceedUrl": "/job/jobname/42/wfapi/inputSubmit?inputId=Deploy", "abortUrl": "/job/jobname/42/input/Deploy/abort"}]`))
+			return
+		}
+		res.WriteHeader(http.StatusNotFound)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	build := &gojenkins.Build{Jenkins: jenkins, Base: "/job/jobname/42"}
+	inputs, err := getPendingInputs(build)
+	assert.Nil(t, err)
+	assert.Len(t, inputs, 1)
+	assert.Equal(t, "Deploy to production?", inputs[0].Message)
+
+	definitions := inputs[0].ParameterDefinitions()
+	assert.Len(t, definitions, 1)
+	assert.Equal(t, "target", definitions[0].Name)
+	assert.Equal(t, "ChoiceParameterDefinition", definitions[0].Type)
+	assert.Equal(t, "Where to deploy", definitions[0].Description)
+	assert.Equal(t, []string{"eu", "us"}, definitions[0].Choices)
+	assert.Equal(t, "eu", definitions[0].DefaultParameterValue.Value)
+
+	input, err := getPendingInput(build, "Deploy")
+	assert.Nil(t, err)
+	assert.Equal(t, "Deploy", input.ID)
+
+	input, err = getPendingInput(build, "Other")
+	assert.Nil(t, err)
+	assert.Nil(t, input)
+
+	_, err = getPendingInputs(&gojenkins.Build{Jenkins: jenkins, Base: "/job/freestyle/1"})
+	assert.Equal(t, errNotAPipeline, err)
+}
+
+func TestSubmitInput(t *testing.T) {
+	var requestPath, requestQuery, requestJSON string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requestPath = req.URL.Path
+		requestQuery = req.URL.RawQuery
+		requestJSON = req.FormValue("json")
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	build := &gojenkins.Build{Jenkins: jenkins, Base: "/job/jobname/42"}
+	input := &pipelineInput{ID: "Approve"}
+
+	assert.Nil(t, submitInput(build, input, true, nil))
+	assert.Equal(t, "/job/jobname/42/input/Approve/proceedEmpty", requestPath)
+
+	assert.Nil(t, submitInput(build, input, false, nil))
+	assert.Equal(t, "/job/jobname/42/input/Approve/abort", requestPath)
+
+	input.Inputs = []pipelineInputParameter{{Type: "StringParameterDefinition", Name: "version"}}
+	assert.Nil(t, submitInput(build, input, true, map[string]string{"version": "1.2.3", "unknown": "value"}))
+	assert.Equal(t, "/job/jobname/42/wfapi/inputSubmit", requestPath)
+	assert.Equal(t, "inputId=Approve", requestQuery)
+
+	var submitted map[string][]map[string]string
+	assert.Nil(t, json.Unmarshal([]byte(requestJSON), &submitted))
+	assert.Equal(t, []map[string]string{{"name": "version", "value": "1.2.3"}}, submitted["parameter"])
+}
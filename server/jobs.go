@@ -0,0 +1,236 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	jobsPerPage = 50
+
+	// maxFolderDepth limits how deep nested folders are fetched.
+	maxFolderDepth = 10
+)
+
+// jobListEntry is a job found while walking the folders of a Jenkins instance.
+type jobListEntry struct {
+	// Name is the full path of the job, including its folders.
+	Name  string
+	URL   string
+	Color string
+}
+
+// jobsTreeEntry is a job of a Jenkins instance, with the jobs it contains if it is a folder.
+type jobsTreeEntry struct {
+	Class string          `json:"_class"`
+	Name  string          `json:"name"`
+	URL   string          `json:"url"`
+	Color string          `json:"color"`
+	Jobs  []jobsTreeEntry `json:"jobs"`
+}
+
+// jobsTree returns the tree query selecting the jobs and the jobs of their folders, down to the given depth.
+func jobsTree(depth int) string {
+	fields := "name,url,color,_class"
+	if depth > 0 {
+		fields += "," + jobsTree(depth-1)
+	}
+	return "jobs[" + fields + "]"
+}
+
+// isFolderClass checks if items of the given class contain other jobs.
+func isFolderClass(class string) bool {
+	return strings.HasSuffix(class, ".Folder") ||
+		strings.HasSuffix(class, "OrganizationFolder") ||
+		strings.HasSuffix(class, "MultiBranchProject")
+}
+
+// listJobs returns all the jobs found in the given folder of a Jenkins instance and its subfolders.
+// The whole instance is listed if the folder is empty.
+func (p *Plugin) listJobs(userID, instance, folder string) ([]jobListEntry, error) {
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
+	if jenkinsErr != nil {
+		return nil, errors.Wrap(jenkinsErr, "Error creating Jenkins client")
+	}
+
+	folder = strings.Trim(folder, "/")
+	endpoint := ""
+	prefix := ""
+	if folder != "" {
+		endpoint = "/job/" + strings.ReplaceAll(folder, "/", "/job/")
+		prefix = folder + "/"
+	}
+
+	// The folders are fetched with the jobs in a single request, rather than one request per folder.
+	response := struct {
+		Jobs []jobsTreeEntry `json:"jobs"`
+	}{}
+	resp, err := jenkins.Requester.GetJSON(endpoint, &response, map[string]string{"tree": jobsTree(maxFolderDepth)})
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error fetching the jobs of '%s'", folder)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching the jobs of '%s'", resp.StatusCode, folder)
+	}
+
+	jobs := []jobListEntry{}
+	walkJobs(response.Jobs, prefix, &jobs)
+	return jobs, nil
+}
+
+// walkJobs adds the jobs of the tree to the list, with their full path, skipping the folders.
+func walkJobs(tree []jobsTreeEntry, prefix string, jobs *[]jobListEntry) {
+	for _, job := range tree {
+		name := prefix + job.Name
+		if isFolderClass(job.Class) {
+			walkJobs(job.Jobs, name+"/", jobs)
+			continue
+		}
+
+		*jobs = append(*jobs, jobListEntry{Name: name, URL: job.URL, Color: job.Color})
+	}
+}
+
+// filterJobs returns the jobs whose full path matches the given regular expression.
+func filterJobs(jobs []jobListEntry, filter *regexp.Regexp) []jobListEntry {
+	filtered := []jobListEntry{}
+	for _, job := range jobs {
+		if filter.MatchString(job.Name) {
+			filtered = append(filtered, job)
+		}
+	}
+	return filtered
+}
+
+// jobColorStatus converts the color of a job, as returned by the Jenkins API, to a readable status.
+func jobColorStatus(color string) string {
+	status := ""
+	switch strings.TrimSuffix(color, "_anime") {
+	case "blue", "green":
+		status = "Success"
+	case "red":
+		status = "Failed"
+	case "yellow":
+		status = "Unstable"
+	case "aborted":
+		status = "Aborted"
+	case "notbuilt", "nobuilt":
+		status = "Not built"
+	case "disabled":
+		status = "Disabled"
+	case "grey":
+		status = "Pending"
+	default:
+		status = "Unknown"
+	}
+
+	if strings.HasSuffix(color, "_anime") {
+		status += " (building)"
+	}
+	return status
+}
+
+// postJobList posts a page of the jobs of a Jenkins instance matching the filter.
+// The jobs are cached, so that browsing the pages doesn't fetch them again.
+func (p *Plugin) postJobList(userID, channelID, instance, folder string, filter *regexp.Regexp, page int) error {
+	jobs, err := p.getCachedJobs(userID, instance, folder)
+	if err != nil {
+		return err
+	}
+
+	if filter != nil {
+		jobs = filterJobs(jobs, filter)
+	}
+
+	if len(jobs) == 0 {
+		p.createPost(userID, channelID, instance, "No jobs found.")
+		return nil
+	}
+
+	pages := (len(jobs) + jobsPerPage - 1) / jobsPerPage
+	if page > pages {
+		p.createPost(userID, channelID, instance, fmt.Sprintf("There are only %d page(s) of jobs.", pages))
+		return nil
+	}
+
+	start := (page - 1) * jobsPerPage
+	end := start + jobsPerPage
+	if end > len(jobs) {
+		end = len(jobs)
+	}
+
+	msg := fmt.Sprintf("Jobs %d-%d of %d (page %d of %d)\n\n", start+1, end, len(jobs), page, pages)
+	msg += "| Job | Status |\n|:--|:--|\n"
+	for _, job := range jobs[start:end] {
+		msg += fmt.Sprintf("| [%s](%s) | %s |\n", escapeTableCell(qualifyJobName(instance, job.Name)), job.URL, jobColorStatus(job.Color))
+	}
+	if page < pages {
+		msg += fmt.Sprintf("\nUse `--page %d` to see the next page.", page+1)
+	}
+
+	p.createPost(userID, channelID, instance, msg)
+	return nil
+}
+
+func (p *Plugin) executeJobsCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	parameters, filterValue, ok := extractFlag(parameters, "filter")
+	if !ok {
+		return p.getCommandResponse(args, "Please specify a regular expression after `--filter`.")
+	}
+
+	parameters, pageValue, ok := extractFlag(parameters, "page")
+	if !ok {
+		return p.getCommandResponse(args, "Please specify a page number after `--page`.")
+	}
+
+	var filter *regexp.Regexp
+	if filterValue != "" {
+		var err error
+		filter, err = regexp.Compile(filterValue)
+		if err != nil {
+			return p.getCommandResponse(args, fmt.Sprintf("Invalid filter: %s", err.Error()))
+		}
+	}
+
+	page := 1
+	if pageValue != "" {
+		var err error
+		page, err = strconv.Atoi(pageValue)
+		if err != nil || page < 1 {
+			return p.getCommandResponse(args, "The page number must be a positive number.")
+		}
+	}
+
+	folder := ""
+	if len(parameters) > 0 {
+		var rest []string
+		folder, rest, ok = splitJobName(parameters)
+		if !ok || len(rest) != 0 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to list jobs.")
+		}
+	}
+
+	folder, ok = qualifyJobSelector(instance, folder)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, folder))
+	}
+	folderInstance, folder := parseJobSelector(folder)
+	if folderInstance != "" {
+		if _, err := p.getConfiguration().getInstanceURL(folderInstance); err != nil {
+			return p.getCommandResponse(args, fmt.Sprintf("Unknown Jenkins instance '%s'.", folderInstance))
+		}
+	}
+
+	if err := p.postJobList(args.UserId, args.ChannelId, folderInstance, folder, filter, page); err != nil {
+		p.API.LogError("Error listing jobs", "folder", folder, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while listing the jobs.")
+	}
+
+	return &model.CommandResponse{}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListJobs(t *testing.T) {
+	var tree string
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		tree = req.URL.Query().Get("tree")
+		switch req.URL.Path {
+		case "/api/json":
+			_, _ = res.Write([]byte(`{"jobs":[
+				{"_class": "hudson.model.FreeStyleProject", "name": "jobname", "url": "http://jenkins/job/jobname/", "color": "blue"},
+				{"_class": "com.cloudbees.hudson.plugins.folder.Folder", "name": "folder1", "url": "http://jenkins/job/folder1/", "jobs": [
+					{"_class": "org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject", "name": "repo", "url": "http://jenkins/job/folder1/job/repo/", "jobs": [
+						{"_class": "org.jenkinsci.plugins.workflow.job.WorkflowJob", "name": "main", "url": "http://jenkins/job/folder1/job/repo/job/main/", "color": "red_anime"}
+					]}
+				]}
+			]}`))
+		case "/job/folder1/api/json":
+			_, _ = res.Write([]byte(`{"jobs":[
+				{"_class": "org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject", "name": "repo", "url": "http://jenkins/job/folder1/job/repo/", "jobs": [
+					{"_class": "org.jenkinsci.plugins.workflow.job.WorkflowJob", "name": "main", "url": "http://jenkins/job/folder1/job/repo/job/main/", "color": "red_anime"}
+				]}
+			]}`))
+		case "/job/secret/api/json":
+			res.WriteHeader(http.StatusForbidden)
+		default:
+			res.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+
+	jobs, err := p.listJobs("user1", "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []jobListEntry{
+		{Name: "jobname", URL: "http://jenkins/job/jobname/", Color: "blue"},
+		{Name: "folder1/repo/main", URL: "http://jenkins/job/folder1/job/repo/job/main/", Color: "red_anime"},
+	}, jobs)
+	assert.Equal(t, jobsTree(maxFolderDepth), tree)
+
+	jobs, err = p.listJobs("user1", "", "folder1/")
+	assert.Nil(t, err)
+	assert.Equal(t, []jobListEntry{
+		{Name: "folder1/repo/main", URL: "http://jenkins/job/folder1/job/repo/job/main/", Color: "red_anime"},
+	}, jobs)
+
+	_, err = p.listJobs("user1", "", "secret")
+	assert.NotNil(t, err)
+
+	_, err = p.listJobs("user1", "", "missing")
+	assert.NotNil(t, err)
+
+	filtered := filterJobs([]jobListEntry{{Name: "jobname"}, {Name: "folder1/repo/main"}}, regexp.MustCompile("^folder1/"))
+	assert.Equal(t, []jobListEntry{{Name: "folder1/repo/main"}}, filtered)
+}
+
+func TestJobsTree(t *testing.T) {
+	assert.Equal(t, "jobs[name,url,color,_class]", jobsTree(0))
+	assert.Equal(t, "jobs[name,url,color,_class,jobs[name,url,color,_class,jobs[name,url,color,_class]]]", jobsTree(2))
+}
+
+func TestJobColorStatus(t *testing.T) {
+	assert.Equal(t, "Success", jobColorStatus("blue"))
+	assert.Equal(t, "Failed (building)", jobColorStatus("red_anime"))
+	assert.Equal(t, "Disabled", jobColorStatus("disabled"))
+	assert.Equal(t, "Unknown", jobColorStatus(""))
+}
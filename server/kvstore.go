@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/pkg/errors"
+)
+
+// kvUpdateAttempts is how many times an update of a KV store value is attempted when it is changed concurrently.
+const kvUpdateAttempts = 10
+
+// updateKV reads the value of the key, changes it with update and stores it only if it wasn't changed meanwhile,
+// by another server of the cluster for example. The update is attempted again on a concurrent change,
+// so update may be called several times. The key is deleted if update returns a nil value.
+func (p *Plugin) updateKV(key string, update func(value []byte) ([]byte, error)) error {
+	for i := 0; i < kvUpdateAttempts; i++ {
+		oldValue, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return appErr
+		}
+
+		newValue, err := update(oldValue)
+		if err != nil {
+			return err
+		}
+
+		var saved bool
+		switch {
+		case newValue == nil && oldValue == nil:
+			return nil
+		case newValue == nil:
+			saved, appErr = p.API.KVCompareAndDelete(key, oldValue)
+		default:
+			saved, appErr = p.API.KVCompareAndSet(key, oldValue, newValue)
+		}
+		if appErr != nil {
+			return appErr
+		}
+		if saved {
+			return nil
+		}
+	}
+
+	return errors.Errorf("failed to update %q, which kept being changed concurrently", key)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateKV(t *testing.T) {
+	t.Run("retries on a concurrent change", func(t *testing.T) {
+		p := &Plugin{}
+		api := &plugintest.API{}
+		p.SetAPI(api)
+
+		api.On("KVGet", "key").Return([]byte("1"), nil).Once()
+		api.On("KVCompareAndSet", "key", []byte("1"), []byte("1+")).Return(false, nil).Once()
+		api.On("KVGet", "key").Return([]byte("2"), nil).Once()
+		api.On("KVCompareAndSet", "key", []byte("2"), []byte("2+")).Return(true, nil).Once()
+
+		calls := 0
+		err := p.updateKV("key", func(value []byte) ([]byte, error) {
+			calls++
+			return append(value, '+'), nil
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, 2, calls)
+		api.AssertExpectations(t)
+	})
+
+	t.Run("deletes the key on a nil value", func(t *testing.T) {
+		p := &Plugin{}
+		api := &plugintest.API{}
+		p.SetAPI(api)
+
+		api.On("KVGet", "key").Return([]byte("1"), nil)
+		api.On("KVCompareAndDelete", "key", []byte("1")).Return(true, nil)
+
+		assert.Nil(t, p.updateKV("key", func(value []byte) ([]byte, error) {
+			return nil, nil
+		}))
+		api.AssertExpectations(t)
+	})
+
+	t.Run("gives up when the value keeps changing", func(t *testing.T) {
+		p := &Plugin{}
+		api := &plugintest.API{}
+		p.SetAPI(api)
+
+		api.On("KVGet", "key").Return(nil, nil)
+		api.On("KVCompareAndSet", "key", []byte(nil), []byte("new")).Return(false, nil)
+
+		err := p.updateKV("key", func(value []byte) ([]byte, error) {
+			return []byte("new"), nil
+		})
+		assert.NotNil(t, err)
+		api.AssertNumberOfCalls(t, "KVCompareAndSet", kvUpdateAttempts)
+	})
+}
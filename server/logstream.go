@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// maxLogChunkSize is the maximum number of characters of console output per post,
+	// leaving room for the code block under the post size limit.
+	maxLogChunkSize = 3500
+
+	// maxLogChunksPerBatch limits the number of posts created for each batch of console output.
+	// Older output of a larger batch is skipped.
+	maxLogChunksPerBatch = 5
+)
+
+// splitLogChunks splits console output into chunks of at most maxSize characters,
+// breaking on line boundaries whenever possible.
+func splitLogChunks(text string, maxSize int) []string {
+	chunks := []string{}
+	current := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for utf8.RuneCountInString(line) > maxSize {
+			if current != "" {
+				chunks = append(chunks, current)
+				current = ""
+			}
+			runes := []rune(line)
+			chunks = append(chunks, string(runes[:maxSize]))
+			line = string(runes[maxSize:])
+		}
+
+		if utf8.RuneCountInString(current)+utf8.RuneCountInString(line) > maxSize {
+			chunks = append(chunks, current)
+			current = ""
+		}
+		current += line
+	}
+
+	if strings.TrimSpace(current) != "" {
+		chunks = append(chunks, current)
+	}
+
+	return chunks
+}
+
+// formatLogChunk renders console output as a code block.
+func formatLogChunk(chunk string) string {
+	// Code fences inside the output would end the code block.
+	chunk = strings.ReplaceAll(chunk, "```", "'''")
+	return "```\n" + strings.TrimSuffix(chunk, "\n") + "\n```"
+}
+
+// followBuildLog streams the console output of a build into the thread of a post, in batches,
+// until the build has finished.
+func (p *Plugin) followBuildLog(userID, channelID, jobName string, build *gojenkins.Build) {
+	instance := jobInstance(jobName)
+	attachment := generateSlackAttachment(fmt.Sprintf("Console log of the build #%d of the job '%s'\nBuild URL : %s", build.GetBuildNumber(), jobName, build.GetUrl()))
+	rootPost := p.createAttachmentPost(userID, channelID, instance, attachment)
+	if rootPost == nil {
+		return
+	}
+
+	var offset int64
+	deadline := time.Now().Add(buildWatchTimeout)
+	for time.Now().Before(deadline) {
+		console, err := build.GetConsoleOutputFromIndex(offset)
+		if err != nil {
+			p.API.LogWarn("Error fetching the console output", "job_name", jobName, "build", build.GetBuildNumber(), "err", err.Error())
+		} else {
+			offset = console.Offset
+			p.postLogChunks(channelID, rootPost.Id, build, console.Content)
+
+			if !console.HasMoreText {
+				p.postLogEnd(channelID, rootPost.Id, build)
+				return
+			}
+		}
+
+		if !p.waitPollingInterval() {
+			return
+		}
+	}
+
+	p.createReplyPost(channelID, rootPost.Id, "Stopped following the console log, the build is still running.")
+}
+
+// postLogChunks posts a batch of console output in the thread.
+func (p *Plugin) postLogChunks(channelID, rootID string, build *gojenkins.Build, content string) {
+	chunks := splitLogChunks(content, maxLogChunkSize)
+	if len(chunks) > maxLogChunksPerBatch {
+		skipped := 0
+		for _, chunk := range chunks[:len(chunks)-maxLogChunksPerBatch] {
+			skipped += strings.Count(chunk, "\n")
+		}
+		p.createReplyPost(channelID, rootID, fmt.Sprintf("Skipped %d lines of output. See the [full console output](%sconsole).", skipped, build.GetUrl()))
+		chunks = chunks[len(chunks)-maxLogChunksPerBatch:]
+	}
+
+	for _, chunk := range chunks {
+		p.createReplyPost(channelID, rootID, formatLogChunk(chunk))
+	}
+}
+
+// postLogEnd posts the result of the build in the thread once its console log is complete.
+func (p *Plugin) postLogEnd(channelID, rootID string, build *gojenkins.Build) {
+	if _, err := build.Poll(); err != nil {
+		p.API.LogWarn("Error polling jenkins build to check the build status", "err", err.Error())
+		p.createReplyPost(channelID, rootID, "The console log is complete.")
+		return
+	}
+
+	p.createReplyPost(channelID, rootID, fmt.Sprintf("The build has finished with status %s.", build.GetResult()))
+}
+
+func (p *Plugin) executeFollowLogCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	jobName, buildNumber, _, ok := parseBuildParameters(parameters)
+	if !ok {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to follow the log of a build.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	build, err := p.getBuild(jobName, args.UserId, buildNumber)
+	if err != nil {
+		p.API.LogError("Error fetching the build", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while fetching the build.")
+	}
+
+	go p.followBuildLog(args.UserId, args.ChannelId, jobName, build)
+
+	return p.getCommandResponse(args, fmt.Sprintf("Following the log of the build #%d of the job '%s'; check the thread in the channel.", build.GetBuildNumber(), jobName))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitLogChunks(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Text     string
+		MaxSize  int
+		Expected []string
+	}{
+		"empty": {
+			Text:     "",
+			MaxSize:  10,
+			Expected: []string{},
+		},
+		"fits in one chunk": {
+			Text:     "line1\nline2\n",
+			MaxSize:  20,
+			Expected: []string{"line1\nline2\n"},
+		},
+		"breaks on lines": {
+			Text:     "line1\nline2\nline3\n",
+			MaxSize:  12,
+			Expected: []string{"line1\nline2\n", "line3\n"},
+		},
+		"splits long lines": {
+			Text:     "abcdefghij\nkl\n",
+			MaxSize:  4,
+			Expected: []string{"abcd", "efgh", "ij\n", "kl\n"},
+		},
+		"keeps a partial last line": {
+			Text:     "line1\nline",
+			MaxSize:  20,
+			Expected: []string{"line1\nline"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			chunks := splitLogChunks(tc.Text, tc.MaxSize)
+			assert.Equal(t, tc.Expected, chunks)
+			assert.Equal(t, strings.TrimSpace(tc.Text), strings.TrimSpace(strings.Join(chunks, "")))
+		})
+	}
+}
+
+func TestFormatLogChunk(t *testing.T) {
+	assert.Equal(t, "```\necho '''\n```", formatLogChunk("echo ```\n"))
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// nodeInfo is a Jenkins node, as returned by the /computer API.
+type nodeInfo struct {
+	DisplayName        string `json:"displayName"`
+	Offline            bool   `json:"offline"`
+	TemporarilyOffline bool   `json:"temporarilyOffline"`
+	OfflineCauseReason string `json:"offlineCauseReason"`
+	Idle               bool   `json:"idle"`
+	NumExecutors       int    `json:"numExecutors"`
+	AssignedLabels     []struct {
+		Name string `json:"name"`
+	} `json:"assignedLabels"`
+	Executors []struct {
+		Idle bool `json:"idle"`
+	} `json:"executors"`
+}
+
+type computerResponse struct {
+	Computer []nodeInfo `json:"computer"`
+}
+
+// State returns whether the node is online, offline or temporarily offline.
+func (n *nodeInfo) State() string {
+	switch {
+	case n.TemporarilyOffline:
+		return "Temporarily offline"
+	case n.Offline:
+		return "Offline"
+	default:
+		return "Online"
+	}
+}
+
+// Labels returns the labels of the node, without the implicit label named after the node itself.
+func (n *nodeInfo) Labels() []string {
+	labels := []string{}
+	for _, label := range n.AssignedLabels {
+		if label.Name != n.DisplayName {
+			labels = append(labels, label.Name)
+		}
+	}
+	return labels
+}
+
+// BusyExecutors returns the number of executors currently running a build.
+func (n *nodeInfo) BusyExecutors() int {
+	busy := 0
+	for _, executor := range n.Executors {
+		if !executor.Idle {
+			busy++
+		}
+	}
+	return busy
+}
+
+// nodeComputerName returns the name of the node in the /computer URLs.
+// The built-in node has a reserved name that differs from its display name.
+func nodeComputerName(name string) string {
+	switch strings.ToLower(name) {
+	case "master", "(master)":
+		return "(master)"
+	case "built-in", "built-in node", "(built-in)":
+		return "(built-in)"
+	default:
+		return url.PathEscape(name)
+	}
+}
+
+// getNodes fetches the nodes of the given Jenkins instance.
+func (p *Plugin) getNodes(userID, instance string) ([]nodeInfo, error) {
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
+	if jenkinsErr != nil {
+		return nil, errors.Wrap(jenkinsErr, "Error creating Jenkins client")
+	}
+
+	response := computerResponse{}
+	query := map[string]string{"tree": "computer[displayName,offline,temporarilyOffline,offlineCauseReason,idle,numExecutors,assignedLabels[name],executors[idle]]"}
+	resp, err := jenkins.Requester.GetJSON("/computer", &response, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching nodes")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching nodes", resp.StatusCode)
+	}
+
+	return response.Computer, nil
+}
+
+// postNodes posts the nodes of the given Jenkins instance with their state and executors.
+func (p *Plugin) postNodes(userID, channelID, instance string) error {
+	nodes, err := p.getNodes(userID, instance)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("%d node(s)\n\n", len(nodes))
+	msg += "| Node | State | Executors | Labels | Offline reason |\n|:--|:--|:--|:--|:--|\n"
+	for _, node := range nodes {
+		busy := node.BusyExecutors()
+		executors := fmt.Sprintf("%d busy, %d idle", busy, node.NumExecutors-busy)
+		msg += fmt.Sprintf("| %s | %s | %s | %s | %s |\n", escapeTableCell(node.DisplayName), node.State(), executors, escapeTableCell(strings.Join(node.Labels(), ", ")), escapeTableCell(node.OfflineCauseReason))
+	}
+
+	p.createPost(userID, channelID, instance, msg)
+	return nil
+}
+
+// setNodeOffline marks a node of the given Jenkins instance as temporarily offline.
+func (p *Plugin) setNodeOffline(userID, instance, name, reason string) error {
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
+	if jenkinsErr != nil {
+		return errors.Wrap(jenkinsErr, "Error creating Jenkins client")
+	}
+
+	node, err := jenkins.GetNode(nodeComputerName(name))
+	if err != nil {
+		return errors.Wrap(err, "Error fetching node")
+	}
+
+	if _, err := node.SetOffline(reason); err != nil {
+		return errors.Wrap(err, "Error taking the node offline")
+	}
+
+	return nil
+}
+
+// setNodeOnline brings a temporarily offline node of the given Jenkins instance back online.
+func (p *Plugin) setNodeOnline(userID, instance, name string) error {
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
+	if jenkinsErr != nil {
+		return errors.Wrap(jenkinsErr, "Error creating Jenkins client")
+	}
+
+	node, err := jenkins.GetNode(nodeComputerName(name))
+	if err != nil {
+		return errors.Wrap(err, "Error fetching node")
+	}
+
+	if _, err := node.SetOnline(); err != nil {
+		return errors.Wrap(err, "Error bringing the node online")
+	}
+
+	return nil
+}
+
+func (p *Plugin) executeNodesCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	if len(parameters) != 0 {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to list nodes.")
+	}
+
+	if err := p.postNodes(args.UserId, args.ChannelId, instance); err != nil {
+		p.API.LogError("Error fetching nodes", "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while fetching the nodes.")
+	}
+
+	return &model.CommandResponse{}
+}
+
+func (p *Plugin) executeNodeCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	if len(parameters) < 2 || (parameters[0] != "offline" && parameters[0] != "online") {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to manage nodes.")
+	}
+
+	// Node names are quoted the same way as job names.
+	name, rest, ok := splitJobName(parameters[1:])
+	if !ok || name == "" {
+		return p.getCommandResponse(args, "Please specify the name of the node.")
+	}
+
+	if parameters[0] == "online" {
+		if len(rest) != 0 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to manage nodes.")
+		}
+
+		if err := p.setNodeOnline(args.UserId, instance, name); err != nil {
+			p.API.LogError("Error bringing the node online", "node", name, "err", err.Error())
+			return p.getCommandResponse(args, fmt.Sprintf("Encountered an error while bringing the node '%s' online.", name))
+		}
+
+		p.createPost(args.UserId, args.ChannelId, instance, fmt.Sprintf("Node '%s' is back online.", name))
+		return &model.CommandResponse{}
+	}
+
+	reason := strings.Join(rest, " ")
+	if reason == "" {
+		reason = "Taken offline from Mattermost"
+	}
+
+	if err := p.setNodeOffline(args.UserId, instance, name, reason); err != nil {
+		p.API.LogError("Error taking the node offline", "node", name, "err", err.Error())
+		return p.getCommandResponse(args, fmt.Sprintf("Encountered an error while taking the node '%s' offline.", name))
+	}
+
+	p.createPost(args.UserId, args.ChannelId, instance, fmt.Sprintf("Node '%s' has been taken offline: %s", name, reason))
+	return &model.CommandResponse{}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNodes(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/computer/api/json" {
+			_, _ = res.Write([]byte(`{"computer":[
+				{"displayName": "Built-In Node", "offline": false, "idle": false, "numExecutors": 2,
+				 "assignedLabels": [{"name": "built-in"}, {"name": "Built-In Node"}], "executors": [{"idle": false}, {"idle": true}]},
+				{"displayName": "agent1", "offline": true, "temporarilyOffline": true, "offlineCauseReason": "Disk replacement", "idle": true, "numExecutors": 1,
+				 "assignedLabels": [{"name": "agent1"}, {"name": "linux"}], "executors": [{"idle": true}]}
+			]}`))
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+
+	nodes, err := p.getNodes("user1", "")
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 2)
+
+	assert.Equal(t, "Online", nodes[0].State())
+	assert.Equal(t, 1, nodes[0].BusyExecutors())
+	assert.Equal(t, []string{"built-in"}, nodes[0].Labels())
+
+	assert.Equal(t, "Temporarily offline", nodes[1].State())
+	assert.Equal(t, 0, nodes[1].BusyExecutors())
+	assert.Equal(t, []string{"linux"}, nodes[1].Labels())
+	assert.Equal(t, "Disk replacement", nodes[1].OfflineCauseReason)
+
+	forbiddenServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusForbidden)
+	}))
+	defer forbiddenServer.Close()
+
+	p, _ = setupTestPlugin(t, forbiddenServer.URL)
+	_, err = p.getNodes("user1", "")
+	assert.NotNil(t, err)
+}
+
+func TestNodeComputerName(t *testing.T) {
+	assert.Equal(t, "(built-in)", nodeComputerName("Built-In Node"))
+	assert.Equal(t, "(master)", nodeComputerName("master"))
+	assert.Equal(t, "agent%201", nodeComputerName("agent 1"))
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	permissionRuleRole    = "role"
+	permissionRuleUser    = "user"
+	permissionRuleGroup   = "group"
+	permissionRuleChannel = "channel"
+)
+
+// permissionValueRegex matches the names of roles, users, groups and channels in permission rules.
+var permissionValueRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// permissionRule allows a subcommand to the users with a role, to a user, to the members of a group
+// or to the users of a channel. The value of a channel rule is the name of the team and the name of the channel,
+// separated by a slash, as channel names are only unique within a team.
+type permissionRule struct {
+	Kind  string
+	Value string
+}
+
+// String formats the rule as written in the plugin settings.
+func (r permissionRule) String() string {
+	switch r.Kind {
+	case permissionRuleUser:
+		return "@" + r.Value
+	case permissionRuleGroup:
+		return "group:" + r.Value
+	case permissionRuleChannel:
+		return "~" + r.Value
+	default:
+		return r.Value
+	}
+}
+
+// parsePermissionRule parses a rule: system_admin or any other role name, @username, group:name or ~team/channel.
+func parsePermissionRule(rule string) (permissionRule, error) {
+	parsed := permissionRule{Kind: permissionRuleRole, Value: rule}
+	names := []string{rule}
+	switch {
+	case strings.HasPrefix(rule, "@"):
+		parsed = permissionRule{Kind: permissionRuleUser, Value: strings.TrimPrefix(rule, "@")}
+		names = []string{parsed.Value}
+	case strings.HasPrefix(rule, "group:"):
+		parsed = permissionRule{Kind: permissionRuleGroup, Value: strings.TrimPrefix(rule, "group:")}
+		names = []string{parsed.Value}
+	case strings.HasPrefix(rule, "~"):
+		parsed = permissionRule{Kind: permissionRuleChannel, Value: strings.TrimPrefix(rule, "~")}
+		names = strings.Split(parsed.Value, "/")
+		if len(names) != 2 {
+			return permissionRule{}, errors.Errorf("invalid rule %q, channels are given as ~team/channel", rule)
+		}
+	}
+
+	for _, name := range names {
+		if !permissionValueRegex.MatchString(name) {
+			return permissionRule{}, errors.Errorf("invalid rule %q, expected a role, @username, group:name or ~team/channel", rule)
+		}
+	}
+	return parsed, nil
+}
+
+// isCommandName checks if the name is a subcommand of /jenkins.
+func isCommandName(name string) bool {
+	for _, command := range commandNames {
+		if command == name {
+			return true
+		}
+	}
+	return false
+}
+
+// getCommandPermissions parses the rules restricting the subcommands of /jenkins.
+// Each line of CommandPermissions is expected in the "command: rule, rule" format. Empty lines are ignored.
+// Subcommands without rules are allowed to everyone.
+func (c *configuration) getCommandPermissions() (map[string][]permissionRule, error) {
+	permissions := map[string][]permissionRule{}
+	for _, line := range strings.Split(c.CommandPermissions, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid command permission %q, expected command: rule, rule", line)
+		}
+
+		command := strings.ToLower(strings.TrimSpace(parts[0]))
+		// An unknown command is rejected, as a typo would otherwise leave the intended command unrestricted.
+		if !isCommandName(command) {
+			return nil, errors.Errorf("unknown command %q in the command permissions", command)
+		}
+		if _, ok := permissions[command]; ok {
+			return nil, errors.Errorf("duplicate command permission for %q", command)
+		}
+
+		rules := []permissionRule{}
+		for _, rule := range strings.Split(parts[1], ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "" {
+				continue
+			}
+
+			parsed, err := parsePermissionRule(rule)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid command permission for %q", command)
+			}
+			rules = append(rules, parsed)
+		}
+		if len(rules) == 0 {
+			return nil, errors.Errorf("no rules in the command permission for %q", command)
+		}
+
+		permissions[command] = rules
+	}
+
+	return permissions, nil
+}
+
+// buildTriggeringCommands are the subcommands which trigger builds without going through build.
+// They are restricted by the rules of build too, so that restricting build can't be bypassed.
+var buildTriggeringCommands = map[string]bool{
+	"rebuild":  true,
+	"run":      true,
+	"schedule": true,
+}
+
+// permissionCommands returns the subcommands whose rules apply to the subcommand.
+func permissionCommands(command string) []string {
+	if buildTriggeringCommands[command] {
+		return []string{command, "build"}
+	}
+	return []string{command}
+}
+
+// checkCommandPermission checks if the user is allowed to run the subcommand in the channel.
+// The user is allowed if any of the rules of the subcommand matches, and any of the rules of build
+// for the subcommands which trigger builds.
+// Returns a message explaining the denial if the user is not allowed.
+func (p *Plugin) checkCommandPermission(userID, channelID, command string) (bool, string) {
+	permissions, err := p.getConfiguration().getCommandPermissions()
+	if err != nil {
+		p.API.LogError("Invalid command permissions", "err", err.Error())
+		return false, "The command permissions of the Jenkins plugin are misconfigured. Please contact your system administrator."
+	}
+
+	for _, permissionCommand := range permissionCommands(command) {
+		rules, ok := permissions[permissionCommand]
+		if !ok {
+			continue
+		}
+
+		if !p.matchPermissionRules(userID, channelID, rules) {
+			allowed := []string{}
+			for _, rule := range rules {
+				allowed = append(allowed, "`"+rule.String()+"`")
+			}
+			return false, fmt.Sprintf("You are not allowed to run `/jenkins %s`. It is restricted to: %s.", permissionCommand, strings.Join(allowed, ", "))
+		}
+	}
+
+	return true, ""
+}
+
+// matchPermissionRules checks if any of the rules matches the user in the channel.
+func (p *Plugin) matchPermissionRules(userID, channelID string, rules []permissionRule) bool {
+	var user *model.User
+	var groups []*model.Group
+	channelName := ""
+	for _, rule := range rules {
+		switch rule.Kind {
+		case permissionRuleRole, permissionRuleUser:
+			if user == nil {
+				var appErr *model.AppError
+				if user, appErr = p.API.GetUser(userID); appErr != nil {
+					p.API.LogWarn("Error fetching the user to check the command permissions", "err", appErr.Error())
+					continue
+				}
+			}
+			if rule.Kind == permissionRuleUser && user.Username == rule.Value {
+				return true
+			}
+			if rule.Kind == permissionRuleRole && user.IsInRole(rule.Value) {
+				return true
+			}
+		case permissionRuleGroup:
+			if groups == nil {
+				var appErr *model.AppError
+				if groups, appErr = p.API.GetGroupsForUser(userID); appErr != nil {
+					p.API.LogWarn("Error fetching the groups of the user to check the command permissions", "err", appErr.Error())
+					continue
+				}
+			}
+			for _, group := range groups {
+				if group.Name != nil && *group.Name == rule.Value {
+					return true
+				}
+			}
+		case permissionRuleChannel:
+			if channelName == "" {
+				var err error
+				if channelName, err = p.getTeamChannelName(channelID); err != nil {
+					p.API.LogWarn("Error fetching the channel to check the command permissions", "err", err.Error())
+					continue
+				}
+			}
+			if channelName == rule.Value {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getTeamChannelName returns the name of the team of the channel and the name of the channel,
+// separated by a slash, as written in channel rules.
+func (p *Plugin) getTeamChannelName(channelID string) (string, error) {
+	channel, appErr := p.API.GetChannel(channelID)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	team, appErr := p.API.GetTeam(channel.TeamId)
+	if appErr != nil {
+		return "", appErr
+	}
+
+	return team.Name + "/" + channel.Name, nil
+}
+
+// actionCommand returns the subcommand whose permissions apply to a button action.
+func actionCommand(action string) string {
+	switch action {
+	case actionInputProceed, actionInputAbort:
+		return "input"
+	default:
+		// The other actions are named after their subcommand.
+		return action
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckCommandPermission(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{CommandPermissions: "delete: system_admin, @alice, group:release-managers, ~engineering/ops\nsafe-restart: system_admin"}, &model.Config{})
+
+	admin, alice, bob, carol := model.NewId(), model.NewId(), model.NewId(), model.NewId()
+	api.On("GetUser", admin).Return(&model.User{Id: admin, Username: "admin", Roles: "system_user system_admin"}, nil)
+	api.On("GetUser", alice).Return(&model.User{Id: alice, Username: "alice", Roles: "system_user"}, nil)
+	api.On("GetUser", bob).Return(&model.User{Id: bob, Username: "bob", Roles: "system_user"}, nil)
+	api.On("GetUser", carol).Return(&model.User{Id: carol, Username: "carol", Roles: "system_user"}, nil)
+	api.On("GetGroupsForUser", bob).Return([]*model.Group{{Name: model.NewString("release-managers")}}, nil)
+	api.On("GetGroupsForUser", carol).Return([]*model.Group{{Name: model.NewString("developers")}}, nil)
+	api.On("GetChannel", "ops-channel").Return(&model.Channel{Id: "ops-channel", TeamId: "engineering-team", Name: "ops"}, nil)
+	api.On("GetChannel", "town-square").Return(&model.Channel{Id: "town-square", TeamId: "engineering-team", Name: "town-square"}, nil)
+	api.On("GetChannel", "other-ops-channel").Return(&model.Channel{Id: "other-ops-channel", TeamId: "sales-team", Name: "ops"}, nil)
+	api.On("GetTeam", "engineering-team").Return(&model.Team{Id: "engineering-team", Name: "engineering"}, nil)
+	api.On("GetTeam", "sales-team").Return(&model.Team{Id: "sales-team", Name: "sales"}, nil)
+
+	for name, tc := range map[string]struct {
+		UserID    string
+		ChannelID string
+		Command   string
+		Allowed   bool
+	}{
+		"unrestricted command":        {UserID: carol, ChannelID: "town-square", Command: "build", Allowed: true},
+		"system admin":                {UserID: admin, ChannelID: "town-square", Command: "delete", Allowed: true},
+		"user":                        {UserID: alice, ChannelID: "town-square", Command: "delete", Allowed: true},
+		"group member":                {UserID: bob, ChannelID: "town-square", Command: "delete", Allowed: true},
+		"channel":                     {UserID: carol, ChannelID: "ops-channel", Command: "delete", Allowed: true},
+		"channel of another team":     {UserID: carol, ChannelID: "other-ops-channel", Command: "delete", Allowed: false},
+		"no matching rule":            {UserID: carol, ChannelID: "town-square", Command: "delete", Allowed: false},
+		"user of another command":     {UserID: alice, ChannelID: "town-square", Command: "safe-restart", Allowed: false},
+		"system admin of the command": {UserID: admin, ChannelID: "ops-channel", Command: "safe-restart", Allowed: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			allowed, denial := p.checkCommandPermission(tc.UserID, tc.ChannelID, tc.Command)
+			assert.Equal(t, tc.Allowed, allowed)
+			if tc.Allowed {
+				assert.Equal(t, "", denial)
+			} else {
+				assert.Contains(t, denial, "You are not allowed to run `/jenkins "+tc.Command+"`")
+			}
+		})
+	}
+
+	t.Run("button actions follow the permissions of their command", func(t *testing.T) {
+		p.setConfiguration(&configuration{CommandPermissions: "abort: system_admin"}, &model.Config{})
+
+		body := `{"user_id": "` + carol + `", "channel_id": "town-square", "context": {"action": "abort", "job": "jobname", "build": "42"}}`
+		r := httptest.NewRequest(http.MethodPost, "/action", strings.NewReader(body))
+		r.Header.Set("Mattermost-User-ID", carol)
+		w := httptest.NewRecorder()
+		p.handleAction(w, r)
+
+		var response model.PostActionIntegrationResponse
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, "You are not allowed to run `/jenkins abort`. It is restricted to: `system_admin`.", response.EphemeralText)
+	})
+
+	t.Run("job creation dialogs follow the permissions of createjob", func(t *testing.T) {
+		p.setConfiguration(&configuration{CommandPermissions: "createjob: system_admin"}, &model.Config{})
+
+		body := `{"user_id": "` + carol + `", "channel_id": "town-square", "submission": {"JobName": "jobname", "ConfigXml": "<project/>"}}`
+		r := httptest.NewRequest(http.MethodPost, "/createJob", strings.NewReader(body))
+		r.Header.Set("Mattermost-User-ID", carol)
+		w := httptest.NewRecorder()
+		p.handleJobCreation(w, r)
+
+		var response model.SubmitDialogResponse
+		assert.Nil(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, "You are not allowed to run `/jenkins createjob`. It is restricted to: `system_admin`.", response.Error)
+	})
+
+	t.Run("commands triggering builds follow the permissions of build", func(t *testing.T) {
+		p.setConfiguration(&configuration{CommandPermissions: "build: ~engineering/ops"}, &model.Config{})
+
+		for _, command := range []string{"build", "rebuild", "run", "schedule"} {
+			allowed, denial := p.checkCommandPermission(carol, "town-square", command)
+			assert.False(t, allowed, command)
+			assert.Equal(t, "You are not allowed to run `/jenkins build`. It is restricted to: `~engineering/ops`.", denial)
+
+			allowed, _ = p.checkCommandPermission(carol, "ops-channel", command)
+			assert.True(t, allowed, command)
+		}
+
+		allowed, _ := p.checkCommandPermission(carol, "town-square", "jobs")
+		assert.True(t, allowed)
+
+		allowed, _ = p.checkCommandPermission(carol, "town-square", actionCommand(actionRebuild))
+		assert.False(t, allowed)
+	})
+
+	t.Run("misconfigured permissions deny every command without failing the activation", func(t *testing.T) {
+		config := &configuration{JenkinsURL: "https://jenkins.example.com", CommandPermissions: "safe-restrat: system_admin"}
+		assert.Nil(t, p.IsValid(config))
+
+		api.On("LogError", "Invalid command permissions", "err", mock.Anything).Return()
+		p.setConfiguration(config, &model.Config{})
+		allowed, denial := p.checkCommandPermission(admin, "town-square", "jobs")
+		assert.False(t, allowed)
+		assert.Contains(t, denial, "misconfigured")
+	})
+}
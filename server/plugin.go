@@ -19,6 +19,7 @@ import (
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 )
 
 const (
@@ -41,15 +42,39 @@ type Plugin struct {
 	// setConfiguration for usage.
 	configuration *configuration
 
+	// jobsCacheLock synchronizes access to jobsCache and jobsFetches.
+	jobsCacheLock sync.Mutex
+
+	// jobsCache holds the jobs of each Jenkins instance per user, for autocompletion.
+	jobsCache map[string]jobsCacheEntry
+
+	// jobsFetches holds the fetches of jobs in progress, so that concurrent autocompletions share them.
+	jobsFetches map[string]*jobsFetch
+
+	// schedulesJob triggers the scheduled builds every minute.
+	schedulesJob *cluster.Job
+
+	// stopPolling is closed when the plugin is deactivated, to stop following builds.
+	stopPolling chan struct{}
+
 	botUserID string
 }
 
 type JenkinsUserInfo struct {
 	UserID   string
+	Instance string
 	Username string
 	Token    string
 }
 
+// jenkinsUserInfoKey returns the KV store key of the user's credentials for the given Jenkins instance.
+func jenkinsUserInfoKey(userID, instance string) string {
+	if instance == "" {
+		return userID + jenkinsTokenKey
+	}
+	return userID + jenkinsTokenKey + "_" + instance
+}
+
 func (p *Plugin) OnActivate() error {
 	p.client = pluginapi.NewClient(p.API, p.Driver)
 
@@ -87,28 +112,48 @@ func (p *Plugin) OnActivate() error {
 	}
 
 	p.router = p.InitAPI()
+	p.stopPolling = make(chan struct{})
+
 	conf := p.getConfiguration()
 	if err := p.IsValid(conf); err != nil {
 		return err
 	}
+
+	// The job is scheduled last, as OnDeactivate isn't called to close it if the activation fails.
+	p.schedulesJob, err = cluster.Schedule(p.API, schedulesJobKey, cluster.MakeWaitForRoundedInterval(time.Minute), p.runSchedules)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule the scheduled builds job")
+	}
 	return nil
 }
 
-func (p *Plugin) IsValid(configuration *configuration) error {
-	if configuration.JenkinsURL == "" {
-		return fmt.Errorf("please add Jenkins URL in plugin settings")
+func (p *Plugin) OnDeactivate() error {
+	if p.stopPolling != nil {
+		close(p.stopPolling)
 	}
 
-	u, err := url.Parse(configuration.JenkinsURL)
+	if p.schedulesJob != nil {
+		if err := p.schedulesJob.Close(); err != nil {
+			p.API.LogWarn("Error closing the scheduled builds job", "err", err.Error())
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) IsValid(configuration *configuration) error {
+	instances, err := configuration.getInstances()
 	if err != nil {
 		return err
 	}
 
-	if u.Scheme == "" {
-		return fmt.Errorf("please add scheme to the URL. HTTP or HTTPS")
+	if configuration.JenkinsURL == "" {
+		if len(instances) == 0 {
+			return fmt.Errorf("please add Jenkins URL in plugin settings")
+		}
+		return nil
 	}
 
-	return nil
+	return validateJenkinsURL(configuration.JenkinsURL)
 }
 
 func (p *Plugin) storeJenkinsUserInfo(info *JenkinsUserInfo) error {
@@ -126,19 +171,19 @@ func (p *Plugin) storeJenkinsUserInfo(info *JenkinsUserInfo) error {
 		return err
 	}
 
-	if err := p.API.KVSet(info.UserID+jenkinsTokenKey, jsonInfo); err != nil {
+	if err := p.API.KVSet(jenkinsUserInfoKey(info.UserID, info.Instance), jsonInfo); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func (p *Plugin) getJenkinsUserInfo(userID string) (*JenkinsUserInfo, error) {
+func (p *Plugin) getJenkinsUserInfo(userID, instance string) (*JenkinsUserInfo, error) {
 	config := p.getConfiguration()
 
 	var userInfo JenkinsUserInfo
 
-	infoBytes, infoErr := p.API.KVGet(userID + jenkinsTokenKey)
+	infoBytes, infoErr := p.API.KVGet(jenkinsUserInfoKey(userID, instance))
 
 	if infoErr != nil {
 		return nil, infoErr
@@ -159,15 +204,19 @@ func (p *Plugin) getJenkinsUserInfo(userID string) (*JenkinsUserInfo, error) {
 }
 
 // verifyJenkinsCredentials verifies the authenticity of the username and token
-// by sending a GET call to the Jenkins URL specified in the config.
-func (p *Plugin) verifyJenkinsCredentials(username, token string) (bool, error) {
-	pluginConfig := p.getConfiguration()
-	u, err := url.Parse(pluginConfig.JenkinsURL)
+// by sending a GET call to the URL of the given Jenkins instance.
+func (p *Plugin) verifyJenkinsCredentials(username, token, instance string) (bool, error) {
+	jenkinsURL, err := p.getConfiguration().getInstanceURL(instance)
 	if err != nil {
 		return false, err
 	}
-	scheme := u.Scheme
-	response, respErr := http.Get(fmt.Sprintf("%s://%s:%s@%s", scheme, username, token, u.Host))
+	u, err := url.Parse(jenkinsURL)
+	if err != nil {
+		return false, err
+	}
+	// The path is kept, as Jenkins may be served under a prefix such as https://host/jenkins.
+	u.User = url.UserPassword(username, token)
+	response, respErr := http.Get(u.String())
 	if respErr != nil {
 		return false, respErr
 	}
@@ -190,14 +239,20 @@ func (p *Plugin) createEphemeralPost(userID, channelID, message string) {
 }
 
 // createPost creates a non epehemeral post
-func (p *Plugin) createPost(userID, channelID, message string, fileIds ...string) {
-	userInfo, userInfoErr := p.getJenkinsUserInfo(userID)
+func (p *Plugin) createPost(userID, channelID, instance, message string, fileIds ...string) {
+	p.createAttachmentPost(userID, channelID, instance, generateSlackAttachment(message), fileIds...)
+}
+
+// createAttachmentPost creates a non epehemeral post with the given attachment
+// and mentions the user of the Jenkins instance who initiated it.
+// Returns the created post, or nil if the post could not be created.
+func (p *Plugin) createAttachmentPost(userID, channelID, instance string, slackAttachment *model.SlackAttachment, fileIds ...string) *model.Post {
+	userInfo, userInfoErr := p.getJenkinsUserInfo(userID, instance)
 	if userInfoErr != nil {
 		p.API.LogError("Error fetching Jenkins user details", "err", userInfoErr.Error())
-		return
+		return nil
 	}
 
-	slackAttachment := generateSlackAttachment(message)
 	slackAttachment.Pretext = fmt.Sprintf("Initiated by Jenkins user: %s", userInfo.Username)
 	post := &model.Post{
 		UserId:    p.botUserID,
@@ -212,20 +267,59 @@ func (p *Plugin) createPost(userID, channelID, message string, fileIds ...string
 		post.FileIds = append(post.FileIds, fileIds[0])
 	}
 
-	if _, err := p.API.CreatePost(post); err != nil {
+	createdPost, err := p.API.CreatePost(post)
+	if err != nil {
 		p.API.LogError("Could not create a post", "user_id", userID, "err", err.Error())
+		return nil
+	}
+	return createdPost
+}
+
+// createReplyPost creates a post from the bot in the thread of the given post.
+func (p *Plugin) createReplyPost(channelID, rootID, message string) {
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelID,
+		RootId:    rootID,
+		Message:   message,
+		Type:      model.PostTypeDefault,
+	}
+
+	if _, err := p.API.CreatePost(post); err != nil {
+		p.API.LogError("Could not create a post", "channel_id", channelID, "root_id", rootID, "err", err.Error())
 	}
 }
 
-// getJenkinsClient creates a Jenkins client given user ID.
-func (p *Plugin) getJenkinsClient(userID string) (*gojenkins.Jenkins, error) {
-	pluginConfig := p.getConfiguration()
-	userInfo, err := p.getJenkinsUserInfo(userID)
+// createBotPost creates a post from the bot which is not initiated by a Jenkins user.
+func (p *Plugin) createBotPost(channelID string, attachment *model.SlackAttachment) {
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelID,
+		Type:      model.PostTypeDefault,
+		Props: map[string]interface{}{
+			"attachments": []*model.SlackAttachment{attachment},
+		},
+	}
+
+	if _, err := p.API.CreatePost(post); err != nil {
+		p.API.LogError("Could not create a post", "channel_id", channelID, "err", err.Error())
+	}
+}
+
+// getJenkinsClient creates a Jenkins client of the given instance for the user.
+// The default instance is used when the instance name is empty.
+func (p *Plugin) getJenkinsClient(userID, instance string) (*gojenkins.Jenkins, error) {
+	jenkinsURL, err := p.getConfiguration().getInstanceURL(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	userInfo, err := p.getJenkinsUserInfo(userID, instance)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error fetching Jenkins user information")
 	}
 
-	jenkins := gojenkins.CreateJenkins(nil, pluginConfig.JenkinsURL, userInfo.Username, userInfo.Token)
+	jenkins := gojenkins.CreateJenkins(nil, jenkinsURL, userInfo.Username, userInfo.Token)
 	_, errJenkins := jenkins.Init()
 	if errJenkins != nil {
 		wrap := errors.Wrap(errJenkins, "Error creating Jenkins client")
@@ -235,8 +329,10 @@ func (p *Plugin) getJenkinsClient(userID string) (*gojenkins.Jenkins, error) {
 }
 
 // getJob returns a Job object given the jobname.
+// The jobname may be prefixed with the name of a Jenkins instance as instance:folder1/jobname.
 func (p *Plugin) getJob(userID, jobName string) (*gojenkins.Job, error) {
-	jenkins, jenkinsErr := p.getJenkinsClient(userID)
+	instance, jobName := parseJobSelector(jobName)
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
 	if jenkinsErr != nil {
 		return nil, errors.Wrap(jenkinsErr, "Error creating Jenkins client")
 	}
@@ -281,7 +377,8 @@ func (p *Plugin) getBuild(jobName, userID, buildID string) (*gojenkins.Build, er
 
 // triggerJenkinsJob triggers a Jenkins build and polls the build in the queue to see if the build has started.
 func (p *Plugin) triggerJenkinsJob(userID, channelID, jobName string, parameters map[string]string) (*gojenkins.Build, error) {
-	jenkins, jenkinsErr := p.getJenkinsClient(userID)
+	instance, jobName := parseJobSelector(jobName)
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
 	if jenkinsErr != nil {
 		return nil, errors.Wrap(jenkinsErr, "Error creating Jenkins client")
 	}
@@ -289,7 +386,7 @@ func (p *Plugin) triggerJenkinsJob(userID, channelID, jobName string, parameters
 	if containsSlash {
 		jobName = strings.ReplaceAll(jobName, "/", "/job/")
 	}
-	buildQueueID, buildErr := p.buildJenkinsJob(jenkins, userID, channelID, jobName, parameters)
+	buildQueueID, buildErr := p.buildJenkinsJob(jenkins, userID, channelID, instance, jobName, parameters)
 	if buildErr != nil {
 		return nil, buildErr
 	}
@@ -300,9 +397,24 @@ func (p *Plugin) triggerJenkinsJob(userID, channelID, jobName string, parameters
 	return build, nil
 }
 
+// parameterValueString converts the value of a build parameter returned by Jenkins to a string.
+// The last boolean return value is false for values which can't be converted, like the ones of file parameters.
+func parameterValueString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
 // buildJenkinsJob starts a given Jenkins build and
 // creates an ephemeral post once the build has been successfully triggered.
-func (p *Plugin) buildJenkinsJob(jenkins *gojenkins.Jenkins, userID, channelID, jobName string, parameters map[string]string) (int64, error) {
+func (p *Plugin) buildJenkinsJob(jenkins *gojenkins.Jenkins, userID, channelID, instance, jobName string, parameters map[string]string) (int64, error) {
 	buildQueueID, buildErr := jenkins.BuildJob(jobName, parameters)
 	if buildErr != nil {
 		return -1, errors.Wrap(buildErr, "Error building job")
@@ -313,7 +425,7 @@ func (p *Plugin) buildJenkinsJob(jenkins *gojenkins.Jenkins, userID, channelID,
 		return -1, errors.Wrap(buildErr, "error building the job as a previous build is still in queue")
 	}
 
-	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been triggered and is in queue.", strings.ReplaceAll(jobName, "/job/", "/")))
+	p.createPost(userID, channelID, instance, fmt.Sprintf("Job '%s' has been triggered and is in queue.", qualifyJobName(instance, strings.ReplaceAll(jobName, "/job/", "/"))))
 	return buildQueueID, nil
 }
 
@@ -328,7 +440,9 @@ func (p *Plugin) checkIfJobHasStarted(jenkins *gojenkins.Jenkins, jobName string
 		if task.Raw.Executable.URL != "" {
 			break
 		}
-		time.Sleep(pollingSleepTime * time.Second)
+		if !p.waitPollingInterval() {
+			return nil, errPollingStopped
+		}
 		if _, err := task.Poll(); err != nil {
 			p.API.LogWarn("Error polling jenkins job to check the build status", "err", err)
 		}
@@ -341,63 +455,121 @@ func (p *Plugin) checkIfJobHasStarted(jenkins *gojenkins.Jenkins, jobName string
 	return buildInfo, nil
 }
 
-// fetchAndUploadArtifactsOfABuild checks if the specified job and build has artifacts and
-// uploads them to MM server if artifacts are present.
-// If build number is not specified, the method checks the last build of the job for artifacts.
-func (p *Plugin) fetchAndUploadArtifactsOfABuild(userID, channelID, jobName, buildID string) error {
-	config := p.API.GetConfig()
-	build, buildErr := p.getBuild(jobName, userID, buildID)
-	if buildErr != nil {
-		return buildErr
+// errPollingStopped is returned when the plugin is deactivated while polling Jenkins.
+var errPollingStopped = errors.New("the plugin has been deactivated")
+
+// waitPollingInterval waits between two polls of Jenkins.
+// Returns false if the plugin is deactivated meanwhile, in which case polling must stop.
+func (p *Plugin) waitPollingInterval() bool {
+	timer := time.NewTimer(pollingInterval)
+	defer timer.Stop()
+
+	select {
+	case <-p.stopPolling:
+		return false
+	case <-timer.C:
+		return true
 	}
+}
 
-	artifacts := build.GetArtifacts()
-	if len(artifacts) == 0 {
-		p.createPost(userID, channelID, fmt.Sprintf("No artifacts found in the build #%d of the job '%s'", build.GetBuildNumber(), jobName))
-	} else {
-		p.createPost(userID, channelID, fmt.Sprintf("%d Artifact(s) found in the build #%d of the job '%s'", len(artifacts), build.GetBuildNumber(), jobName))
+// waitForBuildResult polls the build until it has finished.
+// onRunning, if not nil, is called after each poll while the build is running.
+// Returns an error if the build is still running after buildWatchTimeout, or errPollingStopped
+// if the plugin is deactivated meanwhile.
+func (p *Plugin) waitForBuildResult(build *gojenkins.Build, onRunning func()) error {
+	deadline := time.Now().Add(buildWatchTimeout)
+	for time.Now().Before(deadline) {
+		if _, err := build.Poll(); err != nil {
+			p.API.LogWarn("Error polling jenkins build to check the build status", "err", err)
+		} else if !build.Raw.Building && build.Raw.Result != "" {
+			return nil
+		} else if onRunning != nil {
+			onRunning()
+		}
+		if !p.waitPollingInterval() {
+			return errPollingStopped
+		}
 	}
-	for _, a := range artifacts {
-		fileData, fileDataErr := a.GetData()
-		if fileDataErr != nil {
-			return errors.Wrap(fileDataErr, "Error fetching file data")
+	return errors.New("timed out waiting for the build to finish")
+}
+
+// watchBuild follows a started build until it has finished and posts its result.
+func (p *Plugin) watchBuild(userID, channelID, jobName string, build *gojenkins.Build) {
+	instance := jobInstance(jobName)
+
+	// The prompts of the input steps the Pipeline pauses on are posted once each.
+	postedInputs := map[string]bool{}
+	checkInputs := true
+	onRunning := func() {
+		if !checkInputs {
+			return
 		}
-		p.createEphemeralPost(userID, channelID, fmt.Sprintf("Uploading artifact '%s' ...", a.FileName))
-		fileInfo, fileInfoErr := p.API.UploadFile(fileData, channelID, a.FileName)
-		if fileInfoErr != nil {
-			return errors.Wrap(fileInfoErr, "Error uploading file")
+		err := p.postNewPendingInputs(userID, channelID, jobName, build, postedInputs)
+		if err == errNotAPipeline {
+			checkInputs = false
+		} else if err != nil {
+			p.API.LogWarn("Error checking the pending inputs of the build", "job_name", jobName, "build", build.GetBuildNumber(), "err", err.Error())
+		}
+	}
+
+	err := p.waitForBuildResult(build, onRunning)
+	if err == errPollingStopped {
+		return
+	}
+	if err != nil {
+		p.API.LogWarn("Stopped following the build", "job_name", jobName, "build", build.GetBuildNumber(), "err", err.Error())
+		p.createPost(userID, channelID, instance, fmt.Sprintf("Job '%s' - #%d is still running. Stopped following the build.\nBuild URL : %s", jobName, build.GetBuildNumber(), build.GetUrl()))
+		return
+	}
+
+	p.createAttachmentPost(userID, channelID, instance, p.buildResultAttachment(jobName, build))
+
+	if build.GetResult() == "FAILURE" {
+		if _, err := p.postFailureExcerpt(userID, channelID, jobName, build); err != nil {
+			p.API.LogWarn("Error posting the failure excerpt", "job_name", jobName, "build", build.GetBuildNumber(), "err", err.Error())
 		}
-		p.createPost(userID, channelID, fmt.Sprintf("Artifact '%s' : %s", fileInfo.Name, *config.ServiceSettings.SiteURL+"/api/v4/files/"+fileInfo.Id))
 	}
-	return nil
 }
 
-// getBuildTestResultsURL checks if the specified job and build has test results and
-// creates a post with the test results URL if the  build has test results.
-// If build number is not specified, the method checks the last build of the job for test results.
-func (p *Plugin) getBuildTestResultsURL(userID, channelID, jobName, buildID string) error {
-	build, buildErr := p.getBuild(jobName, userID, buildID)
-	if buildErr != nil {
-		return buildErr
+// buildResultAttachment creates an attachment describing the result of a finished build,
+// with links to its console output and test report.
+func (p *Plugin) buildResultAttachment(jobName string, build *gojenkins.Build) *model.SlackAttachment {
+	attachment := generateSlackAttachment(fmt.Sprintf("Job '%s' - #%d has finished with status %s", jobName, build.GetBuildNumber(), build.GetResult()))
+	attachment.Color = buildResultColor(build.GetResult())
+	attachment.Fields = []*model.SlackAttachmentField{{
+		Title: "Duration",
+		Value: formatDuration(build.GetDuration()),
+		Short: true,
+	}, {
+		Title: "Console",
+		Value: fmt.Sprintf("[Console output](%sconsole)", build.GetUrl()),
+		Short: true,
+	}}
+
+	hasTestResults, err := build.HasTestResults()
+	if err != nil {
+		p.API.LogWarn("Error checking for test results", "job_name", jobName, "err", err.Error())
+	}
+	if hasTestResults {
+		attachment.Fields = append(attachment.Fields, &model.SlackAttachmentField{
+			Title: "Tests",
+			Value: fmt.Sprintf("[Test report](%stestReport)", build.GetUrl()),
+			Short: true,
+		})
 	}
 
-	hasTestResults, hasTestErr := build.HasTestResults()
-	if hasTestErr != nil {
-		return errors.Wrap(hasTestErr, "Error checking for test results")
+	attachment.Actions = []*model.PostAction{
+		p.buildAction("Rebuild", actionRebuild, jobName, build.GetBuildNumber()),
+		p.buildAction("Get log", actionGetLog, jobName, build.GetBuildNumber()),
 	}
-	msg := ""
 	if hasTestResults {
-		job, jobErr := p.getJob(userID, jobName)
-		if jobErr != nil {
-			return jobErr
-		}
-		testReportsURL := fmt.Sprintf("%s%d/testReport", job.Raw.URL, build.GetBuildNumber())
-		msg = fmt.Sprintf("Test reports for the build #%d of the job '%s': %s", build.GetBuildNumber(), jobName, testReportsURL)
-	} else {
-		msg = fmt.Sprintf("Build #%d of the job '%s' doesn't have test reports.", build.GetBuildNumber(), jobName)
+		attachment.Actions = append(attachment.Actions, p.buildAction("Test results", actionTestResults, jobName, build.GetBuildNumber()))
 	}
-	p.createPost(userID, channelID, msg)
-	return nil
+	if len(build.GetArtifacts()) > 0 {
+		attachment.Actions = append(attachment.Actions, p.buildAction("Artifacts", actionGetArtifacts, jobName, build.GetBuildNumber()))
+	}
+
+	return attachment
 }
 
 // disableJob disables a given job.
@@ -450,6 +622,44 @@ func (p *Plugin) checkIfJobAcceptsParameters(userID, jobName string) (bool, erro
 	return false, nil
 }
 
+// jobParameterDefinition is the definition of a build parameter of a job.
+// Unlike gojenkins.ParameterDefinition, it includes the choices of choice parameters.
+type jobParameterDefinition struct {
+	Name                  string   `json:"name"`
+	Type                  string   `json:"type"`
+	Description           string   `json:"description"`
+	Choices               []string `json:"choices"`
+	DefaultParameterValue struct {
+		Value interface{} `json:"value"`
+	} `json:"defaultParameterValue"`
+}
+
+// getJobParameterDefinitions fetches the definitions of the build parameters of a job.
+func getJobParameterDefinitions(job *gojenkins.Job) ([]jobParameterDefinition, error) {
+	var response struct {
+		Property []struct {
+			ParameterDefinitions []jobParameterDefinition `json:"parameterDefinitions"`
+		} `json:"property"`
+	}
+
+	query := map[string]string{
+		"tree": "property[parameterDefinitions[name,type,description,choices,defaultParameterValue[value]]]",
+	}
+	resp, err := job.Jenkins.Requester.GetJSON(job.Base, &response, query)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching the job parameters", resp.StatusCode)
+	}
+
+	definitions := []jobParameterDefinition{}
+	for _, property := range response.Property {
+		definitions = append(definitions, property.ParameterDefinitions...)
+	}
+	return definitions, nil
+}
+
 // createDialogForParameters creates an interactive dialog for the user to input build parameters.
 func (p *Plugin) createDialogForParameters(userID, triggerID, jobName, channelID string) error {
 	job, jobErr := p.getJob(userID, jobName)
@@ -457,19 +667,18 @@ func (p *Plugin) createDialogForParameters(userID, triggerID, jobName, channelID
 		return errors.Wrap(jobErr, "Error fetching job")
 	}
 
-	jobParameters, err := job.GetParameters()
+	jobParameters, err := getJobParameterDefinitions(job)
 	if err != nil {
 		return errors.Wrap(err, "Error fetching job parameters")
 	}
 
 	var dialogElementArr []model.DialogElement
 
-	for i := 0; i < len(jobParameters); i++ {
-		d := model.DialogElement{DisplayName: jobParameters[i].Name, Name: jobParameters[i].Name, Type: "text", SubType: "text"}
-		dialogElementArr = append(dialogElementArr, d)
+	for _, parameter := range jobParameters {
+		dialogElementArr = append(dialogElementArr, dialogElementForParameter(parameter))
 	}
 	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
-	encodedJobName, _ := url.Parse(jobName)
+	encodedJobName := url.QueryEscape(jobName)
 	dialog := model.OpenDialogRequest{
 		TriggerId: triggerID,
 		URL:       fmt.Sprintf("%s/plugins/jenkins/triggerBuild?jobName=%s", siteURL, encodedJobName),
@@ -504,7 +713,8 @@ func (p *Plugin) fetchAndUploadBuildLog(userID, channelID, jobName, buildID stri
 	}
 
 	msg := fmt.Sprintf("Console log of the build #%d of the job '%s'", build.GetBuildNumber(), jobName)
-	p.createPost(userID, channelID, msg, fileInfo.Id)
+	instance := jobInstance(jobName)
+	p.createPost(userID, channelID, instance, msg, fileInfo.Id)
 	return nil
 }
 
@@ -541,10 +751,10 @@ func (p *Plugin) deleteJob(userID, jobName string) error {
 	return nil
 }
 
-// safeRestart safe restarts the Jenkins server.
+// safeRestart safe restarts the Jenkins server of the given instance.
 // Returns an error if the operation fails.
-func (p *Plugin) safeRestart(userID string) error {
-	jenkins, jenkinsErr := p.getJenkinsClient(userID)
+func (p *Plugin) safeRestart(userID, instance string) error {
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
 	if jenkinsErr != nil {
 		return errors.Wrap(jenkinsErr, "Error creating Jenkins client")
 	}
@@ -554,9 +764,9 @@ func (p *Plugin) safeRestart(userID string) error {
 	return nil
 }
 
-// getListOfInstalledPlugins fetches the list of installed plugins on the Jenkins server.
-func (p *Plugin) getListOfInstalledPlugins(userID, channelID string) error {
-	jenkins, jenkinsErr := p.getJenkinsClient(userID)
+// getListOfInstalledPlugins fetches the list of installed plugins on the Jenkins server of the given instance.
+func (p *Plugin) getListOfInstalledPlugins(userID, channelID, instance string) error {
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
 	if jenkinsErr != nil {
 		return errors.Wrap(jenkinsErr, "Error creating Jenkins client")
 	}
@@ -572,20 +782,25 @@ func (p *Plugin) getListOfInstalledPlugins(userID, channelID string) error {
 		}
 		msg += fmt.Sprintf("%d. %s - %s - %s\n", k+1, v.LongName, v.Version, status)
 	}
-	p.createPost(userID, channelID, msg)
+	p.createPost(userID, channelID, instance, msg)
 	return nil
 }
 
-func (p *Plugin) createJob(userID, channelID, triggerID string) error {
-	if err := p.createDialogForJobCreation(userID, channelID, triggerID); err != nil {
+func (p *Plugin) createJob(userID, channelID, triggerID, instance string) error {
+	if err := p.createDialogForJobCreation(userID, channelID, triggerID, instance); err != nil {
 		return err
 	}
 	return nil
 }
 
 // createDialogForJobCreation creates an interactive dialog
-// for the user to input job name and the content of config.xml
-func (p *Plugin) createDialogForJobCreation(userID, channelID, triggerID string) error {
+// for the user to input job name and the content of config.xml.
+// The job name is prefilled with the given Jenkins instance, if any.
+func (p *Plugin) createDialogForJobCreation(userID, channelID, triggerID, instance string) error {
+	defaultJobName := ""
+	if instance != "" {
+		defaultJobName = instance + ":"
+	}
 	config := p.API.GetConfig()
 	dialog := model.OpenDialogRequest{
 		TriggerId: triggerID,
@@ -599,7 +814,8 @@ func (p *Plugin) createDialogForJobCreation(userID, channelID, triggerID string)
 				Name:        "JobName",
 				Type:        "text",
 				SubType:     "text",
-				HelpText:    "Please use double quotes if the job name has spaces in it.",
+				HelpText:    "Please use double quotes if the job name has spaces in it. Prefix the job name with instance: to create it on a named Jenkins instance.",
+				Default:     defaultJobName,
 				MaxLength:   10000, // Should revist this?
 			}, {
 				DisplayName: "Config.xml",
@@ -623,16 +839,17 @@ func (p *Plugin) sendJobCreateRequest(userID, channelID string, parameters map[s
 	jobName := parameters["JobName"]
 	configXML := parameters["ConfigXml"]
 
-	jenkins, jenkinsErr := p.getJenkinsClient(userID)
-	if jenkinsErr != nil {
-		return errors.Wrap(jenkinsErr, "Error creating Jenkins client")
-	}
-
 	jobName, extraParam, _, ok := parseBuildParameters(strings.Split(jobName, " "))
 	if !ok || extraParam != "" {
 		p.createEphemeralPost(userID, channelID, "Please check `/jenkins help` to find help on how to create a job.")
 		return errors.New("error while creating the job")
 	}
+
+	instance, jobName := parseJobSelector(jobName)
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
+	if jenkinsErr != nil {
+		return errors.Wrap(jenkinsErr, "Error creating Jenkins client")
+	}
 	if strings.Contains(jobName, "/") {
 		splitString := strings.Split(jobName, "/")
 		jobName = splitString[len(splitString)-1]
@@ -655,7 +872,7 @@ func (p *Plugin) sendJobCreateRequest(userID, channelID string, parameters map[s
 			p.createEphemeralPost(userID, channelID, "Error creating the job.")
 			return jobErr
 		}
-		p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been created.", job.GetName()))
+		p.createPost(userID, channelID, instance, fmt.Sprintf("Job '%s' has been created.", job.GetName()))
 		return nil
 	}
 	job, err := jenkins.CreateJob(configXML, jobName)
@@ -663,7 +880,7 @@ func (p *Plugin) sendJobCreateRequest(userID, channelID string, parameters map[s
 		p.createEphemeralPost(userID, channelID, "Error creating the job.")
 		return err
 	}
-	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been created", job.GetName()))
+	p.createPost(userID, channelID, instance, fmt.Sprintf("Job '%s' has been created", job.GetName()))
 
 	return nil
 }
@@ -4,11 +4,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/waseem18/gojenkins"
 )
 
 func TestGetJob(t *testing.T) {
@@ -40,7 +44,7 @@ func TestGetJob(t *testing.T) {
 	serverConf := &model.Config{}
 	p.setConfiguration(conf, serverConf)
 
-	c, err := p.getJenkinsClient("user1")
+	c, err := p.getJenkinsClient("user1", "")
 	assert.Nil(t, err)
 	assert.NotNil(t, c)
 
@@ -49,3 +53,199 @@ func TestGetJob(t *testing.T) {
 	assert.NotNil(t, j)
 	assert.Equal(t, "/job/job1", j.Base)
 }
+
+func TestGetJobOfNamedInstance(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	}))
+	assert.NotNil(t, testServer)
+	defer testServer.Close()
+
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+
+	userInfo := &JenkinsUserInfo{
+		UserID:   "user1",
+		Instance: "ci",
+		Username: "username1",
+		Token:    "i1BmOxqUYk_6MtXJNTUtJIQbH2VikZkGPPycfIJhAaY=",
+	}
+
+	kvData, err := json.Marshal(userInfo)
+	assert.Nil(t, err)
+
+	api.On("KVGet", "user1"+jenkinsTokenKey+"_ci").Return(kvData, nil)
+
+	conf := &configuration{
+		JenkinsInstances: "ci=" + testServer.URL,
+		EncryptionKey:    "enckeyenckeyenckeyenckey",
+	}
+	serverConf := &model.Config{}
+	p.setConfiguration(conf, serverConf)
+
+	j, err := p.getJob("user1", "ci:folder1/job1")
+	assert.Nil(t, err)
+	assert.NotNil(t, j)
+	assert.Equal(t, "/job/folder1/job/job1", j.Base)
+
+	_, err = p.getJob("user1", "release:job1")
+	assert.NotNil(t, err)
+}
+
+// setupTestPlugin creates a plugin connected as user1 to the Jenkins server at the given URL.
+func setupTestPlugin(t *testing.T, jenkinsURL string) (*Plugin, *plugintest.API) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+
+	userInfo := &JenkinsUserInfo{
+		UserID:   "user1",
+		Username: "username1",
+		Token:    "i1BmOxqUYk_6MtXJNTUtJIQbH2VikZkGPPycfIJhAaY=",
+	}
+
+	kvData, err := json.Marshal(userInfo)
+	assert.Nil(t, err)
+
+	api.On("KVGet", "user1"+jenkinsTokenKey).Return(kvData, nil)
+
+	conf := &configuration{
+		JenkinsURL:    jenkinsURL,
+		EncryptionKey: "enckeyenckeyenckeyenckey",
+	}
+	p.setConfiguration(conf, &model.Config{})
+
+	return p, api
+}
+
+func TestGetJobParameterDefinitions(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/job/jobname/api/json" {
+			_, _ = res.Write([]byte(`{"property": [{}, {"parameterDefinitions": [
+				{"name": "BRANCH", "type": "StringParameterDefinition", "defaultParameterValue": {"value": "main"}}
+			]}]}`))
+			return
+		}
+		res.WriteHeader(http.StatusForbidden)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	definitions, err := getJobParameterDefinitions(&gojenkins.Job{Jenkins: jenkins, Base: "/job/jobname"})
+	assert.Nil(t, err)
+	assert.Len(t, definitions, 1)
+	assert.Equal(t, "BRANCH", definitions[0].Name)
+
+	_, err = getJobParameterDefinitions(&gojenkins.Job{Jenkins: jenkins, Base: "/job/forbidden"})
+	assert.NotNil(t, err)
+}
+
+func TestWatchBuild(t *testing.T) {
+	defer func(interval time.Duration) { pollingInterval = interval }(pollingInterval)
+	pollingInterval = time.Millisecond
+
+	polls := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch strings.TrimSuffix(req.URL.Path, "/") {
+		case "/job/jobname/42/api/json":
+			polls++
+			if polls == 1 {
+				_, _ = res.Write([]byte(`{"number": 42, "building": true, "url": "http://jenkins/job/jobname/42/"}`))
+				return
+			}
+			_, _ = res.Write([]byte(`{"number": 42, "building": false, "result": "SUCCESS", "duration": 61000,
+				"url": "http://jenkins/job/jobname/42/", "artifacts": [{"fileName": "app.zip", "relativePath": "app.zip"}]}`))
+		case "/job/jobname/42/testReport/api/json":
+			_, _ = res.Write([]byte(`{"passCount": 3}`))
+		default:
+			res.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	p, api := setupTestPlugin(t, testServer.URL)
+	siteURL := "https://mattermost.example.com"
+	config := &model.Config{}
+	config.ServiceSettings.SiteURL = &siteURL
+	api.On("GetConfig").Return(config)
+
+	var attachment *model.SlackAttachment
+	api.On("CreatePost", mock.Anything).Run(func(args mock.Arguments) {
+		attachment = args.Get(0).(*model.Post).Props["attachments"].([]*model.SlackAttachment)[0]
+	}).Return(&model.Post{}, nil)
+
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+	build := &gojenkins.Build{Jenkins: jenkins, Base: "/job/jobname/42", Raw: &gojenkins.BuildResponse{}}
+
+	p.watchBuild("user1", "channel1", "jobname", build)
+	assert.Equal(t, 2, polls)
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+
+	assert.Equal(t, "Job 'jobname' - #42 has finished with status SUCCESS", attachment.Text)
+	assert.Equal(t, buildResultColor("SUCCESS"), attachment.Color)
+	fields := []string{}
+	for _, field := range attachment.Fields {
+		fields = append(fields, field.Title+": "+field.Value.(string))
+	}
+	assert.Equal(t, []string{
+		"Duration: 1m1s",
+		"Console: [Console output](http://jenkins/job/jobname/42/console)",
+		"Tests: [Test report](http://jenkins/job/jobname/42/testReport)",
+	}, fields)
+	actions := []string{}
+	for _, action := range attachment.Actions {
+		actions = append(actions, action.Name)
+	}
+	assert.Equal(t, []string{"Rebuild", "Get log", "Test results", "Artifacts"}, actions)
+}
+
+func TestWaitForBuildResultStopsOnDeactivate(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		_, _ = res.Write([]byte(`{"number": 42, "building": true}`))
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	p.stopPolling = make(chan struct{})
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+	build := &gojenkins.Build{Jenkins: jenkins, Base: "/job/jobname/42", Raw: &gojenkins.BuildResponse{}}
+
+	done := make(chan error)
+	go func() {
+		done <- p.waitForBuildResult(build, nil)
+	}()
+
+	assert.Nil(t, p.OnDeactivate())
+	select {
+	case err := <-done:
+		assert.Equal(t, errPollingStopped, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the build was still polled after the plugin was deactivated")
+	}
+}
+
+func TestVerifyJenkinsCredentials(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		username, token, ok := req.BasicAuth()
+		if req.URL.Path != "/jenkins" || !ok || username != "user" || token != "token" {
+			res.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL+"/jenkins")
+
+	verified, err := p.verifyJenkinsCredentials("user", "token", "")
+	assert.Nil(t, err)
+	assert.True(t, verified)
+
+	verified, err = p.verifyJenkinsCredentials("user", "wrong", "")
+	assert.NotNil(t, err)
+	assert.False(t, verified)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// queueItem is an item of the Jenkins build queue.
+type queueItem struct {
+	ID       int64
+	JobName  string
+	Why      string
+	Since    time.Time
+	QueuedBy string
+}
+
+// getQueueItems fetches the items of the build queue of the given Jenkins instance.
+// Job names are prefixed with the instance.
+func (p *Plugin) getQueueItems(userID, instance string) ([]queueItem, error) {
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
+	if jenkinsErr != nil {
+		return nil, errors.Wrap(jenkinsErr, "Error creating Jenkins client")
+	}
+
+	queue, err := jenkins.GetQueue()
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the build queue")
+	}
+
+	items := []queueItem{}
+	for _, task := range queue.Raw.Items {
+		jobName := jobNameFromURL(task.Task.URL)
+		if jobName == "" {
+			jobName = task.Task.Name
+		}
+
+		item := queueItem{
+			ID:      task.ID,
+			JobName: qualifyJobName(instance, jobName),
+			Why:     task.Why,
+			Since:   time.Unix(0, task.InQueueSince*int64(time.Millisecond)),
+		}
+		for _, action := range task.Actions {
+			for _, cause := range action.Causes {
+				if description, ok := cause["shortDescription"].(string); ok && item.QueuedBy == "" {
+					item.QueuedBy = description
+				}
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// postBuildQueue posts the items of the build queue of the given Jenkins instance.
+func (p *Plugin) postBuildQueue(userID, channelID, instance string) error {
+	items, err := p.getQueueItems(userID, instance)
+	if err != nil {
+		return err
+	}
+
+	if len(items) == 0 {
+		p.createPost(userID, channelID, instance, "The build queue is empty.")
+		return nil
+	}
+
+	msg := fmt.Sprintf("%d item(s) in the build queue\n\n", len(items))
+	msg += "| ID | Job | In queue for | Why | Queued by |\n|:--|:--|:--|:--|:--|\n"
+	for _, item := range items {
+		msg += fmt.Sprintf("| %d | %s | %s | %s | %s |\n", item.ID, escapeTableCell(item.JobName), time.Since(item.Since).Round(time.Second), escapeTableCell(item.Why), escapeTableCell(item.QueuedBy))
+	}
+	p.createPost(userID, channelID, instance, msg)
+	return nil
+}
+
+// cancelQueueItems removes items from the build queue of the given Jenkins instance.
+// The selector is either the ID of a queue item or a job name, in which case all the queued builds of the job are removed.
+// Returns the IDs of the removed items.
+func (p *Plugin) cancelQueueItems(userID, instance, selector string) ([]int64, error) {
+	ids := []int64{}
+	if id, err := strconv.ParseInt(selector, 10, 64); err == nil {
+		ids = append(ids, id)
+	} else {
+		if selectorInstance := jobInstance(selector); selectorInstance != "" {
+			instance = selectorInstance
+		}
+
+		items, err := p.getQueueItems(userID, instance)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if item.JobName == qualifyJobName(instance, selector) {
+				ids = append(ids, item.ID)
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	jenkins, jenkinsErr := p.getJenkinsClient(userID, instance)
+	if jenkinsErr != nil {
+		return nil, errors.Wrap(jenkinsErr, "Error creating Jenkins client")
+	}
+
+	for _, id := range ids {
+		task, err := jenkins.GetQueueItem(id)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Error fetching queue item %d", id)
+		}
+		if task.Raw.ID == 0 {
+			return nil, errors.Errorf("queue item %d not found", id)
+		}
+
+		if _, err := task.Cancel(); err != nil {
+			return nil, errors.Wrapf(err, "Error cancelling queue item %d", id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (p *Plugin) executeQueueCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	if len(parameters) == 0 {
+		if err := p.postBuildQueue(args.UserId, args.ChannelId, instance); err != nil {
+			p.API.LogError("Error fetching the build queue", "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error while fetching the build queue.")
+		}
+		return &model.CommandResponse{}
+	}
+
+	if parameters[0] != "cancel" {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to inspect the build queue.")
+	}
+
+	selector, rest, ok := splitJobName(parameters[1:])
+	if !ok || selector == "" || len(rest) != 0 {
+		return p.getCommandResponse(args, "Please specify the ID of a queue item or a job name to remove from the build queue.")
+	}
+	if _, ok = qualifyJobSelector(instance, selector); !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, selector))
+	}
+
+	ids, err := p.cancelQueueItems(args.UserId, instance, selector)
+	if err != nil {
+		p.API.LogError("Error cancelling queue items", "selector", selector, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while removing the item from the build queue.")
+	}
+
+	if len(ids) == 0 {
+		return p.getCommandResponse(args, fmt.Sprintf("No queued builds found for '%s'.", selector))
+	}
+
+	idList := []string{}
+	for _, id := range ids {
+		idList = append(idList, strconv.FormatInt(id, 10))
+	}
+	p.createPost(args.UserId, args.ChannelId, jobInstance(qualifyJobName(instance, selector)), fmt.Sprintf("Queue item(s) %s have been removed from the build queue.", strings.Join(idList, ", ")))
+	return &model.CommandResponse{}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetQueueItems(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/queue/api/json" {
+			_, _ = res.Write([]byte(`{"items":[{
+				"id": 42,
+				"why": "Waiting for next available executor",
+				"inQueueSince": 1700000000000,
+				"task": {"name": "jobname", "url": "http://jenkins/job/folder1/job/jobname/"},
+				"actions": [{"causes": [{"shortDescription": "Started by user admin"}]}]
+			}]}`))
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+
+	items, err := p.getQueueItems("user1", "")
+	assert.Nil(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, int64(42), items[0].ID)
+	assert.Equal(t, "folder1/jobname", items[0].JobName)
+	assert.Equal(t, "Waiting for next available executor", items[0].Why)
+	assert.Equal(t, "Started by user admin", items[0].QueuedBy)
+	assert.Equal(t, int64(1700000000000), items[0].Since.UnixMilli())
+}
+
+func TestPostBuildQueue(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/queue/api/json" {
+			_, _ = res.Write([]byte(`{"items":[{
+				"id": 42,
+				"why": "Waiting for next available executor",
+				"inQueueSince": 1700000000000,
+				"task": {"name": "jobname", "url": "http://jenkins/job/team|ops/job/jobname/"}
+			}]}`))
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	p, api := setupTestPlugin(t, testServer.URL)
+	var post *model.Post
+	api.On("CreatePost", mock.Anything).Run(func(args mock.Arguments) {
+		post = args.Get(0).(*model.Post)
+	}).Return(&model.Post{}, nil)
+
+	assert.Nil(t, p.postBuildQueue("user1", "channel1", ""))
+	assert.Contains(t, post.Props["attachments"].([]*model.SlackAttachment)[0].Text, `| 42 | team\|ops/jobname |`)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+// rebuildJob triggers the job again with the parameters of the given build, replaced by the overrides.
+// The last build of the job is used if buildID is an empty string.
+func (p *Plugin) rebuildJob(userID, channelID, jobName, buildID string, overrides map[string]string) (*gojenkins.Build, error) {
+	build, err := p.getBuild(jobName, userID, buildID)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters, err := getBuildParameters(build)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range overrides {
+		if parameters == nil {
+			parameters = map[string]string{}
+		}
+		parameters[name] = value
+	}
+
+	return p.triggerJenkinsJob(userID, channelID, jobName, parameters)
+}
+
+// getBuildParameters returns the parameters a build was triggered with.
+// Password parameters are not returned by Jenkins, so they are left out.
+func getBuildParameters(build *gojenkins.Build) (map[string]string, error) {
+	// gojenkins expects parameter values to be strings, which fails for boolean parameters.
+	response := struct {
+		Actions []struct {
+			Parameters []struct {
+				Name  string      `json:"name"`
+				Value interface{} `json:"value"`
+			} `json:"parameters"`
+		} `json:"actions"`
+	}{}
+	query := map[string]string{"tree": "actions[parameters[name,value]]"}
+	resp, err := build.Jenkins.Requester.GetJSON(build.Base, &response, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the build parameters")
+	}
+	// Rebuilding with the default parameters would be unexpected, so failures are not ignored.
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching the build parameters", resp.StatusCode)
+	}
+
+	var parameters map[string]string
+	for _, action := range response.Actions {
+		for _, parameter := range action.Parameters {
+			value, ok := parameterValueString(parameter.Value)
+			if !ok {
+				continue
+			}
+
+			if parameters == nil {
+				parameters = map[string]string{}
+			}
+			parameters[parameter.Name] = value
+		}
+	}
+
+	return parameters, nil
+}
+
+func (p *Plugin) executeRebuildCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	if len(parameters) == 0 {
+		return p.getCommandResponse(args, jobNotSpecifiedResponse)
+	}
+
+	jobName, buildNumber, overrides, ok := parseBuildParameters(parameters)
+	if !ok {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to rebuild a build.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	go func() {
+		build, err := p.rebuildJob(args.UserId, args.ChannelId, jobName, buildNumber, overrides)
+		if err != nil {
+			p.API.LogError("Error rebuilding the job", "job_name", jobName, "build", buildNumber, "err", err.Error())
+			p.createPost(args.UserId, args.ChannelId, jobInstance(jobName), fmt.Sprintf("Error rebuilding the job '%s'.", jobName))
+			return
+		}
+		p.followBuild(args.UserId, args.ChannelId, jobName, build)
+	}()
+
+	if buildNumber == "" {
+		return p.getCommandResponse(args, fmt.Sprintf("Rebuilding the last build of the job '%s'; check channel for updates.", jobName))
+	}
+	return p.getCommandResponse(args, fmt.Sprintf("Rebuilding the build #%s of the job '%s'; check channel for updates.", buildNumber, jobName))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waseem18/gojenkins"
+)
+
+func TestGetBuildParameters(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/job/jobname/42/api/json" {
+			_, _ = res.Write([]byte(`{"actions":[{}, {"parameters":[
+				{"name": "BRANCH", "value": "main"},
+				{"name": "DRY_RUN", "value": true},
+				{"name": "RETRIES", "value": 3},
+				{"name": "SECRET"}
+			]}]}`))
+			return
+		}
+		res.WriteHeader(http.StatusForbidden)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	parameters, err := getBuildParameters(&gojenkins.Build{Jenkins: jenkins, Base: "/job/jobname/42"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"BRANCH": "main", "DRY_RUN": "true", "RETRIES": "3"}, parameters)
+
+	_, err = getBuildParameters(&gojenkins.Build{Jenkins: jenkins, Base: "/job/forbidden/42"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	jenkinsSchedulesKey = "_jenkinsSchedules"
+
+	// schedulesJobKey is the key of the cluster job running the scheduled builds.
+	schedulesJobKey = "jenkins_schedules"
+
+	scheduleIDLength = 8
+
+	// scheduleCatchUp is how long a schedule is still triggered after a missed run,
+	// when the job was delayed or not running on any server for example.
+	scheduleCatchUp = time.Hour
+)
+
+// Schedule is a build of a job triggered periodically in a channel, with the credentials of the user who created it.
+type Schedule struct {
+	ID         string
+	ChannelID  string
+	CreatorID  string
+	Job        string
+	Cron       string
+	Parameters map[string]string
+
+	// Timezone is the timezone of the creator the cron expression is evaluated in.
+	Timezone string
+
+	// LastRun is the minute the schedule was created or last triggered at, so that it is triggered once per minute
+	// at most and the runs missed since then can be caught up.
+	LastRun time.Time
+}
+
+// Schedules holds all the schedules, keyed by ID.
+type Schedules struct {
+	Schedules map[string]*Schedule
+}
+
+// Location returns the location the cron expression of the schedule is evaluated in.
+func (s *Schedule) Location() *time.Location {
+	if s.Timezone == "" {
+		return time.UTC
+	}
+
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// IsDue checks if the schedule must be triggered at the minute of the given time.
+// The minutes since the last run are checked too, up to scheduleCatchUp, so that a delayed or skipped check
+// doesn't drop a run. Several missed runs are caught up by a single one.
+func (s *Schedule) IsDue(now time.Time) bool {
+	minute := now.Truncate(time.Minute)
+	if !s.LastRun.Before(minute) {
+		return false
+	}
+
+	cron, err := parseCron(s.Cron)
+	if err != nil {
+		return false
+	}
+
+	from := minute
+	if !s.LastRun.IsZero() {
+		from = s.LastRun.Truncate(time.Minute).Add(time.Minute)
+		if earliest := minute.Add(-scheduleCatchUp); from.Before(earliest) {
+			from = earliest
+		}
+	}
+
+	location := s.Location()
+	for t := from; !t.After(minute); t = t.Add(time.Minute) {
+		if cron.Matches(t.In(location)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plugin) getSchedules() (*Schedules, error) {
+	schedulesBytes, appErr := p.API.KVGet(jenkinsSchedulesKey)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return parseSchedules(schedulesBytes)
+}
+
+func parseSchedules(schedulesBytes []byte) (*Schedules, error) {
+	schedules := &Schedules{Schedules: map[string]*Schedule{}}
+	if schedulesBytes == nil {
+		return schedules, nil
+	}
+
+	if err := json.Unmarshal(schedulesBytes, schedules); err != nil {
+		return nil, err
+	}
+
+	if schedules.Schedules == nil {
+		schedules.Schedules = map[string]*Schedule{}
+	}
+
+	return schedules, nil
+}
+
+// updateSchedules changes the schedules with update and stores them.
+// The schedules are stored only if they weren't changed meanwhile by another server of the cluster,
+// otherwise update is called again with the new schedules.
+func (p *Plugin) updateSchedules(update func(schedules *Schedules) error) error {
+	return p.updateKV(jenkinsSchedulesKey, func(schedulesBytes []byte) ([]byte, error) {
+		schedules, err := parseSchedules(schedulesBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := update(schedules); err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(schedules)
+	})
+}
+
+// addSchedule stores a new schedule and assigns it an ID.
+func (p *Plugin) addSchedule(schedule *Schedule) error {
+	schedule.ID = model.NewId()[:scheduleIDLength]
+	// The runs before the creation of the schedule must not be caught up.
+	schedule.LastRun = time.Now().Truncate(time.Minute)
+
+	return p.updateSchedules(func(schedules *Schedules) error {
+		schedules.Schedules[schedule.ID] = schedule
+		return nil
+	})
+}
+
+// removeSchedule removes the schedule with the given ID, if it was created in the channel.
+// Returns false if there is no such schedule.
+func (p *Plugin) removeSchedule(channelID, scheduleID string) (bool, error) {
+	removed := false
+	err := p.updateSchedules(func(schedules *Schedules) error {
+		schedule, ok := schedules.Schedules[scheduleID]
+		removed = ok && schedule.ChannelID == channelID
+		if removed {
+			delete(schedules.Schedules, scheduleID)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return removed, nil
+}
+
+// getChannelSchedules returns the schedules created in the channel, sorted by job.
+func (p *Plugin) getChannelSchedules(channelID string) ([]*Schedule, error) {
+	schedules, err := p.getSchedules()
+	if err != nil {
+		return nil, err
+	}
+
+	channelSchedules := []*Schedule{}
+	for _, schedule := range schedules.Schedules {
+		if schedule.ChannelID == channelID {
+			channelSchedules = append(channelSchedules, schedule)
+		}
+	}
+
+	sort.Slice(channelSchedules, func(i, j int) bool {
+		if channelSchedules[i].Job != channelSchedules[j].Job {
+			return channelSchedules[i].Job < channelSchedules[j].Job
+		}
+		return channelSchedules[i].ID < channelSchedules[j].ID
+	})
+
+	return channelSchedules, nil
+}
+
+// runSchedules triggers the schedules which are due. It runs every minute on a single server of the cluster.
+func (p *Plugin) runSchedules() {
+	now := time.Now()
+
+	// The schedules which are due are marked as run before being triggered,
+	// so that they are triggered once even if their update conflicts with another one.
+	var due []Schedule
+	err := p.updateSchedules(func(schedules *Schedules) error {
+		due = []Schedule{}
+		for _, schedule := range schedules.Schedules {
+			if schedule.IsDue(now) {
+				schedule.LastRun = now.Truncate(time.Minute)
+				due = append(due, *schedule)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		p.API.LogError("Error updating the schedules", "err", err.Error())
+		return
+	}
+
+	for _, schedule := range due {
+		go p.runSchedule(schedule)
+	}
+}
+
+// runSchedule triggers the build of a schedule and follows it in the channel of the schedule.
+// The permissions of the creator are checked again, as they may have changed since the schedule was created.
+func (p *Plugin) runSchedule(schedule Schedule) {
+	if allowed, _ := p.checkCommandPermission(schedule.CreatorID, schedule.ChannelID, "schedule"); !allowed {
+		p.API.LogWarn("Skipping a scheduled build not allowed to its creator", "schedule", schedule.ID, "job_name", schedule.Job)
+		p.createPost(schedule.CreatorID, schedule.ChannelID, jobInstance(schedule.Job), fmt.Sprintf("The scheduled build `%s` of the job '%s' has been skipped, as its creator is no longer allowed to run it.", schedule.ID, schedule.Job))
+		return
+	}
+
+	build, err := p.triggerJenkinsJob(schedule.CreatorID, schedule.ChannelID, schedule.Job, schedule.Parameters)
+	if err != nil {
+		p.API.LogError("Error triggering the scheduled build", "schedule", schedule.ID, "job_name", schedule.Job, "err", err.Error())
+		p.createPost(schedule.CreatorID, schedule.ChannelID, jobInstance(schedule.Job), fmt.Sprintf("Error triggering the scheduled build `%s` of the job '%s'.", schedule.ID, schedule.Job))
+		return
+	}
+	p.followBuild(schedule.CreatorID, schedule.ChannelID, schedule.Job, build)
+}
+
+// formatSchedule formats a schedule as an item of a list.
+func formatSchedule(schedule *Schedule) string {
+	msg := fmt.Sprintf("* `%s` - `%s` at `%s` (%s)", schedule.ID, schedule.Job, schedule.Cron, schedule.Location())
+	if len(schedule.Parameters) > 0 {
+		parameters := []string{}
+		for name, value := range schedule.Parameters {
+			parameters = append(parameters, name+"="+value)
+		}
+		sort.Strings(parameters)
+		msg += " with " + strings.Join(parameters, ", ")
+	}
+	return msg
+}
+
+func (p *Plugin) executeScheduleCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	jobName, rest, ok := splitJobName(parameters)
+	if !ok || jobName == "" || len(rest) == 0 {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to schedule a build.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	// The cron expression is quoted like a job name with spaces.
+	cronExpression, rest, _ := splitJobName(rest)
+	if _, err := parseCron(cronExpression); err != nil {
+		return p.getCommandResponse(args, fmt.Sprintf("Invalid cron expression '%s': %s. Use 5 fields like `\"0 2 * * 1-5\"`.", cronExpression, err.Error()))
+	}
+
+	if _, err := p.getJob(args.UserId, jobName); err != nil {
+		p.API.LogError("Error fetching the job to schedule", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, fmt.Sprintf("Error fetching the job '%s'. Make sure it exists and you are connected to Jenkins.", jobName))
+	}
+
+	schedule := &Schedule{
+		ChannelID:  args.ChannelId,
+		CreatorID:  args.UserId,
+		Job:        jobName,
+		Cron:       cronExpression,
+		Parameters: parseKeyValueParameters(rest),
+	}
+	if user, appErr := p.API.GetUser(args.UserId); appErr == nil {
+		schedule.Timezone = user.GetPreferredTimezone()
+	}
+
+	if err := p.addSchedule(schedule); err != nil {
+		p.API.LogError("Error saving the schedule", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while saving the schedule.")
+	}
+
+	return p.getCommandResponse(args, fmt.Sprintf("The job '%s' has been scheduled at `%s` (%s) in this channel with the ID `%s`.", jobName, schedule.Cron, schedule.Location(), schedule.ID))
+}
+
+func (p *Plugin) executeSchedulesCommand(args *model.CommandArgs) *model.CommandResponse {
+	schedules, err := p.getChannelSchedules(args.ChannelId)
+	if err != nil {
+		p.API.LogError("Error fetching the schedules", "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while fetching the schedules.")
+	}
+
+	if len(schedules) == 0 {
+		return p.getCommandResponse(args, "There are no scheduled builds in this channel.")
+	}
+
+	msg := "###### Scheduled builds of this channel\n"
+	for _, schedule := range schedules {
+		msg += formatSchedule(schedule) + "\n"
+	}
+
+	return p.getCommandResponse(args, msg)
+}
+
+func (p *Plugin) executeUnscheduleCommand(parameters []string, args *model.CommandArgs) *model.CommandResponse {
+	if len(parameters) != 1 {
+		return p.getCommandResponse(args, "Please specify the ID of the schedule to remove. Use `/jenkins schedules` to find it.")
+	}
+
+	removed, err := p.removeSchedule(args.ChannelId, parameters[0])
+	if err != nil {
+		p.API.LogError("Error removing the schedule", "schedule", parameters[0], "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while removing the schedule.")
+	}
+
+	if !removed {
+		return p.getCommandResponse(args, fmt.Sprintf("There is no schedule with the ID `%s` in this channel.", parameters[0]))
+	}
+
+	return p.getCommandResponse(args, fmt.Sprintf("The schedule `%s` has been removed.", parameters[0]))
+}
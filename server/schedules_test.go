@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScheduleIsDue(t *testing.T) {
+	now := time.Date(2024, 3, 4, 2, 0, 25, 0, time.UTC)
+
+	schedule := &Schedule{Cron: "0 2 * * *"}
+	assert.True(t, schedule.IsDue(now))
+	assert.False(t, schedule.IsDue(now.Add(time.Minute)))
+
+	schedule.LastRun = now.Truncate(time.Minute)
+	assert.False(t, schedule.IsDue(now), "a schedule is triggered once per minute at most")
+
+	schedule = &Schedule{Cron: "0 2 * * *", Timezone: "Europe/Paris"}
+	assert.False(t, schedule.IsDue(now))
+	assert.True(t, schedule.IsDue(now.Add(-time.Hour)))
+
+	schedule = &Schedule{Cron: "0 2 * * *", Timezone: "Unknown/Timezone"}
+	assert.True(t, schedule.IsDue(now))
+	assert.Equal(t, time.UTC, schedule.Location())
+
+	schedule = &Schedule{Cron: "invalid"}
+	assert.False(t, schedule.IsDue(now))
+}
+
+func TestScheduleIsDueCatchUp(t *testing.T) {
+	now := time.Date(2024, 3, 4, 2, 5, 10, 0, time.UTC)
+
+	schedule := &Schedule{Cron: "0 2 * * *", LastRun: time.Date(2024, 3, 3, 2, 0, 0, 0, time.UTC)}
+	assert.True(t, schedule.IsDue(now), "a run missed a few minutes ago is caught up")
+
+	schedule.LastRun = now.Truncate(time.Minute).Add(-3 * time.Minute)
+	assert.False(t, schedule.IsDue(now), "a run is not caught up twice")
+
+	schedule.LastRun = time.Date(2024, 3, 4, 1, 30, 0, 0, time.UTC)
+	assert.False(t, schedule.IsDue(now.Add(2*time.Hour)), "a run missed longer ago than the catch-up is dropped")
+
+	schedule = &Schedule{Cron: "0 2 * * *"}
+	assert.False(t, schedule.IsDue(now), "schedules without runs only check the current minute")
+}
+
+func TestFormatSchedule(t *testing.T) {
+	schedule := &Schedule{ID: "abcd1234", Job: "folder/smoke", Cron: "0 2 * * *"}
+	assert.Equal(t, "* `abcd1234` - `folder/smoke` at `0 2 * * *` (UTC)", formatSchedule(schedule))
+
+	schedule.Timezone = "Europe/Paris"
+	schedule.Parameters = map[string]string{"env": "staging", "dry_run": "true"}
+	assert.Equal(t, "* `abcd1234` - `folder/smoke` at `0 2 * * *` (Europe/Paris) with dry_run=true, env=staging", formatSchedule(schedule))
+}
+
+func TestRunScheduleNotAllowed(t *testing.T) {
+	requests := 0
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		requests++
+	}))
+	defer testServer.Close()
+
+	p, api := setupTestPlugin(t, testServer.URL)
+	p.setConfiguration(&configuration{
+		JenkinsURL:         testServer.URL,
+		EncryptionKey:      "enckeyenckeyenckeyenckey",
+		CommandPermissions: "build: system_admin",
+	}, &model.Config{})
+
+	api.On("GetUser", "user1").Return(&model.User{Id: "user1", Username: "user1", Roles: "system_user"}, nil)
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+		attachments := post.Props["attachments"].([]*model.SlackAttachment)
+		return strings.Contains(attachments[0].Text, "The scheduled build `abcd1234` of the job 'smoke' has been skipped")
+	})).Return(&model.Post{}, nil)
+
+	p.runSchedule(Schedule{ID: "abcd1234", ChannelID: "channel1", CreatorID: "user1", Job: "smoke", Cron: "* * * * *"})
+	assert.Equal(t, 0, requests)
+	api.AssertCalled(t, "CreatePost", mock.Anything)
+}
+
+func TestRemoveSchedule(t *testing.T) {
+	p, api := setupTestPlugin(t, "https://jenkins.example.com")
+
+	schedulesBytes, err := json.Marshal(&Schedules{Schedules: map[string]*Schedule{
+		"abcd1234": {ID: "abcd1234", ChannelID: "channel1", Job: "smoke", Cron: "0 2 * * *"},
+	}})
+	assert.Nil(t, err)
+	emptyBytes, err := json.Marshal(&Schedules{Schedules: map[string]*Schedule{}})
+	assert.Nil(t, err)
+
+	api.On("KVGet", jenkinsSchedulesKey).Return(schedulesBytes, nil)
+	api.On("KVCompareAndSet", jenkinsSchedulesKey, schedulesBytes, schedulesBytes).Return(true, nil)
+	api.On("KVCompareAndSet", jenkinsSchedulesKey, schedulesBytes, emptyBytes).Return(true, nil)
+
+	removed, err := p.removeSchedule("channel2", "abcd1234")
+	assert.Nil(t, err)
+	assert.False(t, removed, "a schedule can only be removed from its channel")
+
+	removed, err = p.removeSchedule("channel1", "abcd1234")
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	api.AssertCalled(t, "KVCompareAndSet", jenkinsSchedulesKey, schedulesBytes, emptyBytes)
+}
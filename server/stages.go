@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+// pipelineRun is a run of a Pipeline, as returned by the wfapi/describe API of the Pipeline Stage View plugin.
+type pipelineRun struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Status         string          `json:"status"`
+	DurationMillis int64           `json:"durationMillis"`
+	Stages         []pipelineStage `json:"stages"`
+}
+
+type pipelineStage struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	DurationMillis int64  `json:"durationMillis"`
+	Error          *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"error"`
+}
+
+// Failed checks if the stage failed the run.
+func (s *pipelineStage) Failed() bool {
+	return s.Status == "FAILED" || s.Status == "UNSTABLE" || s.Status == "ABORTED"
+}
+
+// FailedStage returns the first stage which failed the run, or nil if no stage failed.
+func (r *pipelineRun) FailedStage() *pipelineStage {
+	for i := range r.Stages {
+		if r.Stages[i].Failed() {
+			return &r.Stages[i]
+		}
+	}
+	return nil
+}
+
+// pipelineStatusResult converts the status of a Pipeline run or stage to the matching build result.
+func pipelineStatusResult(status string) string {
+	if status == "FAILED" {
+		return "FAILURE"
+	}
+	return status
+}
+
+// getPipelineRun fetches the stages of a Pipeline build.
+func getPipelineRun(build *gojenkins.Build) (*pipelineRun, error) {
+	// GetPipelineRun of gojenkins appends api/json to the wfapi URL, which Jenkins doesn't serve.
+	run := &pipelineRun{}
+	response, err := build.Jenkins.Requester.Get(build.Base+"/wfapi/describe", run, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the Pipeline stages")
+	}
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, errors.New("the build is not a Pipeline run or the Pipeline Stage View plugin is not installed")
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching the Pipeline stages", response.StatusCode)
+	}
+
+	return run, nil
+}
+
+// pipelineStagesAttachment renders the stages of a Pipeline run as a table, with the failing stage highlighted.
+func pipelineStagesAttachment(jobName string, buildNumber int64, buildURL string, run *pipelineRun) *model.SlackAttachment {
+	text := fmt.Sprintf("Stages of the build #%d of the job '%s'\nBuild URL : %s\n\n", buildNumber, jobName, buildURL)
+	if len(run.Stages) == 0 {
+		text += "The build has no stages."
+	} else {
+		failed := run.FailedStage()
+		text += "| Stage | Status | Duration |\n|:--|:--|:--|\n"
+		for i, stage := range run.Stages {
+			if failed != nil && &run.Stages[i] == failed {
+				text += fmt.Sprintf("| **%s** | **%s** | %s |\n", escapeTableCell(stage.Name), stage.Status, formatDuration(stage.DurationMillis))
+				continue
+			}
+			text += fmt.Sprintf("| %s | %s | %s |\n", escapeTableCell(stage.Name), stage.Status, formatDuration(stage.DurationMillis))
+		}
+
+		if failed != nil {
+			text += fmt.Sprintf("\nFailing stage: **%s**", failed.Name)
+			if failed.Error != nil && failed.Error.Message != "" {
+				text += fmt.Sprintf(" - %s", firstLine(failed.Error.Message))
+			}
+		}
+	}
+
+	attachment := generateSlackAttachment(text)
+	attachment.Color = buildResultColor(pipelineStatusResult(run.Status))
+	attachment.Fields = []*model.SlackAttachmentField{
+		{Title: "Status", Value: run.Status, Short: true},
+		{Title: "Duration", Value: formatDuration(run.DurationMillis), Short: true},
+	}
+
+	return attachment
+}
+
+// postPipelineStages posts the stages of a Pipeline build.
+// The last build of the job is used if buildID is an empty string.
+func (p *Plugin) postPipelineStages(userID, channelID, jobName, buildID string) error {
+	build, err := p.getBuild(jobName, userID, buildID)
+	if err != nil {
+		return err
+	}
+
+	run, err := getPipelineRun(build)
+	if err != nil {
+		return err
+	}
+
+	p.createAttachmentPost(userID, channelID, jobInstance(jobName), pipelineStagesAttachment(jobName, build.GetBuildNumber(), build.GetUrl(), run))
+	return nil
+}
+
+func (p *Plugin) executeStagesCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	jobName, buildNumber, _, ok := parseBuildParameters(parameters)
+	if !ok {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get the stages of a build.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	if err := p.postPipelineStages(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
+		p.API.LogError("Error fetching the Pipeline stages", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while fetching the stages of the build. Make sure the job is a Pipeline.")
+	}
+
+	return &model.CommandResponse{}
+}
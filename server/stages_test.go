@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waseem18/gojenkins"
+)
+
+func TestPipelineStagesAttachment(t *testing.T) {
+	run := &pipelineRun{
+		Status:         "FAILED",
+		DurationMillis: 95000,
+		Stages: []pipelineStage{
+			{Name: "Checkout", Status: "SUCCESS", DurationMillis: 2000},
+			{Name: "Build | Test", Status: "FAILED", DurationMillis: 90000},
+			{Name: "Deploy", Status: "NOT_EXECUTED"},
+		},
+	}
+	run.Stages[1].Error = &struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	}{Message: "script returned exit code 1\nmore details", Type: "hudson.AbortException"}
+
+	attachment := pipelineStagesAttachment("folder/jobname", 42, "http://jenkins/job/folder/job/jobname/42/", run)
+	assert.Contains(t, attachment.Text, "Stages of the build #42 of the job 'folder/jobname'")
+	assert.Contains(t, attachment.Text, "| Checkout | SUCCESS | 2s |\n")
+	assert.Contains(t, attachment.Text, "| **Build \\| Test** | **FAILED** | 1m30s |\n")
+	assert.Contains(t, attachment.Text, "| Deploy | NOT_EXECUTED | 0s |\n")
+	assert.Contains(t, attachment.Text, "Failing stage: **Build | Test** - script returned exit code 1")
+	assert.NotContains(t, attachment.Text, "more details")
+	assert.Equal(t, buildResultColor("FAILURE"), attachment.Color)
+
+	run = &pipelineRun{Status: "IN_PROGRESS", Stages: []pipelineStage{{Name: "Checkout", Status: "IN_PROGRESS"}}}
+	attachment = pipelineStagesAttachment("jobname", 43, "http://jenkins/job/jobname/43/", run)
+	assert.NotContains(t, attachment.Text, "Failing stage")
+	assert.Equal(t, buildResultColor("IN_PROGRESS"), attachment.Color)
+}
+
+func TestGetPipelineRun(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if strings.TrimSuffix(req.URL.Path, "/") == "/job/jobname/42/wfapi/describe" {
+			_, _ = res.Write([]byte(`{"id": "42", "name": "#42", "status": "SUCCESS", "durationMillis": 3000,
+				"stages": [{"id": "6", "name": "Build", "status": "SUCCESS", "durationMillis": 2500}]}`))
+			return
+		}
+		res.WriteHeader(http.StatusNotFound)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	run, err := getPipelineRun(&gojenkins.Build{Jenkins: jenkins, Base: "/job/jobname/42"})
+	assert.Nil(t, err)
+	assert.Equal(t, "SUCCESS", run.Status)
+	assert.Equal(t, []pipelineStage{{ID: "6", Name: "Build", Status: "SUCCESS", DurationMillis: 2500}}, run.Stages)
+	assert.Nil(t, run.FailedStage())
+
+	_, err = getPipelineRun(&gojenkins.Build{Jenkins: jenkins, Base: "/job/freestyle/1"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/waseem18/gojenkins"
+)
+
+// postJobStatus posts a card with the current status of a job and its recent builds.
+func (p *Plugin) postJobStatus(userID, channelID, jobName string) error {
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return err
+	}
+
+	var lastBuild *gojenkins.Build
+	if job.Raw.LastBuild.Number > 0 {
+		lastBuild, err = job.GetLastBuild()
+		if err != nil {
+			p.API.LogWarn("Error fetching last build", "job_name", jobName, "err", err.Error())
+		}
+	}
+
+	p.createAttachmentPost(userID, channelID, jobInstance(jobName), jobStatusAttachment(jobName, job.Raw, lastBuild))
+	return nil
+}
+
+// jobStatusAttachment renders the status of a job as an attachment.
+// lastBuild may be nil if the job has never been built.
+func jobStatusAttachment(jobName string, job *gojenkins.JobResponse, lastBuild *gojenkins.Build) *model.SlackAttachment {
+	attachment := generateSlackAttachment("")
+	attachment.Title = fmt.Sprintf("Status of the job '%s'", jobName)
+	attachment.TitleLink = job.URL
+
+	lastBuildValue := "Never built"
+	if lastBuild != nil {
+		result := lastBuild.GetResult()
+		if lastBuild.Raw.Building {
+			result = "BUILDING"
+		} else {
+			attachment.Color = buildResultColor(result)
+		}
+		lastBuildValue = fmt.Sprintf("[#%d](%s) - %s", lastBuild.GetBuildNumber(), lastBuild.GetUrl(), result)
+	}
+
+	health := "N/A"
+	if len(job.HealthReport) > 0 {
+		health = fmt.Sprintf("%s (%d%%)", job.HealthReport[0].Description, job.HealthReport[0].Score)
+	}
+
+	disabled := "No"
+	if job.Color == "disabled" {
+		disabled = "Yes"
+	}
+
+	building := "No"
+	if strings.HasSuffix(job.Color, "_anime") || (lastBuild != nil && lastBuild.Raw.Building) {
+		building = "Yes"
+	}
+
+	estimatedDuration := "N/A"
+	if lastBuild != nil && lastBuild.Raw.EstimatedDuration > 0 {
+		estimatedDuration = formatDuration(lastBuild.Raw.EstimatedDuration)
+	}
+
+	attachment.Fields = []*model.SlackAttachmentField{
+		{Title: "Last build", Value: lastBuildValue, Short: true},
+		{Title: "Health", Value: health, Short: true},
+		{Title: "Last successful build", Value: formatJobBuild(job.LastSuccessfulBuild), Short: true},
+		{Title: "Last failed build", Value: formatJobBuild(job.LastFailedBuild), Short: true},
+		{Title: "Last stable build", Value: formatJobBuild(job.LastStableBuild), Short: true},
+		{Title: "Estimated duration", Value: estimatedDuration, Short: true},
+		{Title: "Disabled", Value: disabled, Short: true},
+		{Title: "Building", Value: building, Short: true},
+	}
+
+	return attachment
+}
+
+// formatJobBuild formats a build reference of a job as a link.
+func formatJobBuild(build gojenkins.JobBuild) string {
+	if build.Number == 0 {
+		return "None"
+	}
+	return fmt.Sprintf("[#%d](%s)", build.Number, build.URL)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waseem18/gojenkins"
+)
+
+func TestJobStatusAttachment(t *testing.T) {
+	job := &gojenkins.JobResponse{
+		URL:                 "https://jenkins.example.com/job/main/",
+		Color:               "red_anime",
+		LastSuccessfulBuild: gojenkins.JobBuild{Number: 40, URL: "https://jenkins.example.com/job/main/40/"},
+		LastFailedBuild:     gojenkins.JobBuild{Number: 41, URL: "https://jenkins.example.com/job/main/41/"},
+	}
+	job.HealthReport = append(job.HealthReport, struct {
+		Description   string `json:"description"`
+		IconClassName string `json:"iconClassName"`
+		IconUrl       string `json:"iconUrl"`
+		Score         int64  `json:"score"`
+	}{Description: "Build stability: 1 out of the last 5 builds failed.", Score: 80})
+
+	lastBuild := &gojenkins.Build{Raw: &gojenkins.BuildResponse{
+		Number:            42,
+		URL:               "https://jenkins.example.com/job/main/42/",
+		Building:          true,
+		EstimatedDuration: 90000,
+	}}
+
+	attachment := jobStatusAttachment("main", job, lastBuild)
+	fields := map[string]string{}
+	for _, field := range attachment.Fields {
+		fields[field.Title] = field.Value.(string)
+	}
+
+	assert.Equal(t, "https://jenkins.example.com/job/main/", attachment.TitleLink)
+	assert.Equal(t, "[#42](https://jenkins.example.com/job/main/42/) - BUILDING", fields["Last build"])
+	assert.Equal(t, "[#40](https://jenkins.example.com/job/main/40/)", fields["Last successful build"])
+	assert.Equal(t, "None", fields["Last stable build"])
+	assert.Equal(t, "Build stability: 1 out of the last 5 builds failed. (80%)", fields["Health"])
+	assert.Equal(t, "1m30s", fields["Estimated duration"])
+	assert.Equal(t, "No", fields["Disabled"])
+	assert.Equal(t, "Yes", fields["Building"])
+
+	attachment = jobStatusAttachment("main", &gojenkins.JobResponse{Color: "disabled"}, nil)
+	fields = map[string]string{}
+	for _, field := range attachment.Fields {
+		fields[field.Title] = field.Value.(string)
+	}
+	assert.Equal(t, "Never built", fields["Last build"])
+	assert.Equal(t, "Yes", fields["Disabled"])
+	assert.Equal(t, "N/A", fields["Estimated duration"])
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const jenkinsSubscriptionsKey = "_jenkinsSubscriptions"
+
+const (
+	eventStarted  = "started"
+	eventSuccess  = "success"
+	eventFailure  = "failure"
+	eventUnstable = "unstable"
+	eventAborted  = "aborted"
+)
+
+// subscriptionEvents lists the build events a channel can subscribe to.
+var subscriptionEvents = []string{eventStarted, eventSuccess, eventFailure, eventUnstable, eventAborted}
+
+// Subscription is a channel's subscription to the build events of a job or a folder.
+type Subscription struct {
+	ChannelID string
+	CreatorID string
+	Job       string
+	Events    []string
+}
+
+// Subscriptions holds the subscriptions of every channel, keyed by channel ID.
+type Subscriptions struct {
+	Channels map[string][]*Subscription
+}
+
+// Matches checks if the subscription covers the given job.
+// A job pattern ending with "/*" matches every job inside the folder, including nested folders.
+func (s *Subscription) Matches(jobName string) bool {
+	if s.Job == "*" {
+		return true
+	}
+
+	if strings.HasSuffix(s.Job, "/*") {
+		return strings.HasPrefix(jobName, strings.TrimSuffix(s.Job, "*"))
+	}
+
+	return s.Job == jobName
+}
+
+// HasEvent checks if the subscription includes the given build event.
+func (s *Subscription) HasEvent(event string) bool {
+	for _, e := range s.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSubscriptionEvents parses a comma separated list of build events.
+// All events are returned if the list is empty.
+func parseSubscriptionEvents(eventList string) ([]string, error) {
+	if eventList == "" {
+		return subscriptionEvents, nil
+	}
+
+	events := []string{}
+	for _, event := range strings.Split(eventList, ",") {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event == "" {
+			continue
+		}
+
+		valid := false
+		for _, e := range subscriptionEvents {
+			if e == event {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, errors.Errorf("unknown event '%s'", event)
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (p *Plugin) getSubscriptions() (*Subscriptions, error) {
+	subscriptionsBytes, appErr := p.API.KVGet(jenkinsSubscriptionsKey)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	return parseSubscriptions(subscriptionsBytes)
+}
+
+func parseSubscriptions(subscriptionsBytes []byte) (*Subscriptions, error) {
+	subscriptions := &Subscriptions{Channels: map[string][]*Subscription{}}
+	if subscriptionsBytes == nil {
+		return subscriptions, nil
+	}
+
+	if err := json.Unmarshal(subscriptionsBytes, subscriptions); err != nil {
+		return nil, err
+	}
+
+	if subscriptions.Channels == nil {
+		subscriptions.Channels = map[string][]*Subscription{}
+	}
+
+	return subscriptions, nil
+}
+
+// updateSubscriptions changes the subscriptions with update and stores them.
+// The subscriptions are stored only if they weren't changed meanwhile by another server of the cluster,
+// otherwise update is called again with the new subscriptions.
+func (p *Plugin) updateSubscriptions(update func(subscriptions *Subscriptions) error) error {
+	return p.updateKV(jenkinsSubscriptionsKey, func(subscriptionsBytes []byte) ([]byte, error) {
+		subscriptions, err := parseSubscriptions(subscriptionsBytes)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := update(subscriptions); err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(subscriptions)
+	})
+}
+
+// addSubscription adds a subscription to a channel.
+// An existing subscription of the channel to the same job is replaced.
+func (p *Plugin) addSubscription(subscription *Subscription) error {
+	return p.updateSubscriptions(func(subscriptions *Subscriptions) error {
+		channelSubscriptions := []*Subscription{}
+		for _, s := range subscriptions.Channels[subscription.ChannelID] {
+			if s.Job != subscription.Job {
+				channelSubscriptions = append(channelSubscriptions, s)
+			}
+		}
+		subscriptions.Channels[subscription.ChannelID] = append(channelSubscriptions, subscription)
+		return nil
+	})
+}
+
+// removeSubscription removes the subscription of a channel to the given job.
+// Returns false if the channel was not subscribed to the job.
+func (p *Plugin) removeSubscription(channelID, jobName string) (bool, error) {
+	removed := false
+	err := p.updateSubscriptions(func(subscriptions *Subscriptions) error {
+		removed = false
+		channelSubscriptions := []*Subscription{}
+		for _, s := range subscriptions.Channels[channelID] {
+			if s.Job == jobName {
+				removed = true
+				continue
+			}
+			channelSubscriptions = append(channelSubscriptions, s)
+		}
+
+		if len(channelSubscriptions) == 0 {
+			delete(subscriptions.Channels, channelID)
+		} else {
+			subscriptions.Channels[channelID] = channelSubscriptions
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return removed, nil
+}
+
+// getSubscribedChannels returns the IDs of the channels subscribed to the given event of a job.
+func (p *Plugin) getSubscribedChannels(jobName, event string) ([]string, error) {
+	subscriptions, err := p.getSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+
+	channelIDs := []string{}
+	for channelID, channelSubscriptions := range subscriptions.Channels {
+		for _, s := range channelSubscriptions {
+			if s.Matches(jobName) && s.HasEvent(event) {
+				channelIDs = append(channelIDs, channelID)
+				break
+			}
+		}
+	}
+	sort.Strings(channelIDs)
+
+	return channelIDs, nil
+}
+
+func (p *Plugin) executeSubscribeCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	jobName, rest, ok := splitJobName(parameters)
+	if !ok || jobName == "" || len(rest) > 1 {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to subscribe to a job.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	eventList := ""
+	if len(rest) == 1 {
+		eventList = rest[0]
+	}
+
+	events, err := parseSubscriptionEvents(eventList)
+	if err != nil {
+		return p.getCommandResponse(args, fmt.Sprintf("Invalid events: %s. Available events are: %s.", err.Error(), strings.Join(subscriptionEvents, ", ")))
+	}
+
+	subscription := &Subscription{
+		ChannelID: args.ChannelId,
+		CreatorID: args.UserId,
+		Job:       jobName,
+		Events:    events,
+	}
+	if err := p.addSubscription(subscription); err != nil {
+		p.API.LogError("Error saving the subscription", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while saving the subscription.")
+	}
+
+	return p.getCommandResponse(args, fmt.Sprintf("This channel has been subscribed to '%s' for the events: %s.", jobName, strings.Join(events, ", ")))
+}
+
+func (p *Plugin) executeUnsubscribeCommand(parameters []string, instance string, args *model.CommandArgs) *model.CommandResponse {
+	jobName, rest, ok := splitJobName(parameters)
+	if !ok || jobName == "" || len(rest) != 0 {
+		return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to unsubscribe from a job.")
+	}
+	jobName, ok = qualifyJobSelector(instance, jobName)
+	if !ok {
+		return p.getCommandResponse(args, instanceMismatchResponse(instance, jobName))
+	}
+
+	removed, err := p.removeSubscription(args.ChannelId, jobName)
+	if err != nil {
+		p.API.LogError("Error removing the subscription", "job_name", jobName, "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while removing the subscription.")
+	}
+
+	if !removed {
+		return p.getCommandResponse(args, fmt.Sprintf("This channel is not subscribed to '%s'.", jobName))
+	}
+
+	return p.getCommandResponse(args, fmt.Sprintf("This channel has been unsubscribed from '%s'.", jobName))
+}
+
+func (p *Plugin) executeSubscriptionsCommand(args *model.CommandArgs) *model.CommandResponse {
+	subscriptions, err := p.getSubscriptions()
+	if err != nil {
+		p.API.LogError("Error fetching subscriptions", "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error while fetching the subscriptions.")
+	}
+
+	channelSubscriptions := subscriptions.Channels[args.ChannelId]
+	if len(channelSubscriptions) == 0 {
+		return p.getCommandResponse(args, "This channel is not subscribed to any job.")
+	}
+
+	msg := "###### Subscriptions of this channel\n"
+	for _, s := range channelSubscriptions {
+		msg += fmt.Sprintf("* `%s` - %s\n", s.Job, strings.Join(s.Events, ", "))
+	}
+
+	return p.getCommandResponse(args, msg)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscriptionMatches(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Pattern  string
+		JobName  string
+		Expected bool
+	}{
+		"exact match":              {"folder1/jobname", "folder1/jobname", true},
+		"different job":            {"folder1/jobname", "folder1/other", false},
+		"folder wildcard":          {"folder1/*", "folder1/jobname", true},
+		"nested folder wildcard":   {"folder1/*", "folder1/folder2/jobname", true},
+		"wildcard of other folder": {"folder1/*", "folder10/jobname", false},
+		"global wildcard":          {"*", "folder1/jobname", true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := &Subscription{Job: tc.Pattern}
+			assert.Equal(t, tc.Expected, s.Matches(tc.JobName))
+		})
+	}
+}
+
+func TestParseSubscriptionEvents(t *testing.T) {
+	events, err := parseSubscriptionEvents("")
+	assert.Nil(t, err)
+	assert.Equal(t, subscriptionEvents, events)
+
+	events, err = parseSubscriptionEvents("Started,failure, unstable")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{eventStarted, eventFailure, eventUnstable}, events)
+
+	_, err = parseSubscriptionEvents("started,deployed")
+	assert.NotNil(t, err)
+}
+
+func TestAddAndRemoveSubscription(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+
+	// Another server of the cluster subscribes a channel concurrently.
+	concurrentBytes, err := json.Marshal(&Subscriptions{Channels: map[string][]*Subscription{
+		"channel2": {{ChannelID: "channel2", Job: "other", Events: []string{eventFailure}}},
+	}})
+	assert.Nil(t, err)
+
+	subscription := &Subscription{ChannelID: "channel1", Job: "jobname", Events: []string{eventSuccess}}
+	bothBytes, err := json.Marshal(&Subscriptions{Channels: map[string][]*Subscription{
+		"channel1": {subscription},
+		"channel2": {{ChannelID: "channel2", Job: "other", Events: []string{eventFailure}}},
+	}})
+	assert.Nil(t, err)
+	ownBytes, err := json.Marshal(&Subscriptions{Channels: map[string][]*Subscription{"channel1": {subscription}}})
+	assert.Nil(t, err)
+
+	api.On("KVGet", jenkinsSubscriptionsKey).Return(nil, nil).Once()
+	api.On("KVCompareAndSet", jenkinsSubscriptionsKey, []byte(nil), ownBytes).Return(false, nil).Once()
+	api.On("KVGet", jenkinsSubscriptionsKey).Return(concurrentBytes, nil).Once()
+	api.On("KVCompareAndSet", jenkinsSubscriptionsKey, concurrentBytes, bothBytes).Return(true, nil).Once()
+
+	assert.Nil(t, p.addSubscription(subscription))
+	api.AssertExpectations(t)
+
+	api.On("KVGet", jenkinsSubscriptionsKey).Return(bothBytes, nil).Once()
+	api.On("KVCompareAndSet", jenkinsSubscriptionsKey, bothBytes, concurrentBytes).Return(true, nil).Once()
+
+	removed, err := p.removeSubscription("channel1", "jobname")
+	assert.Nil(t, err)
+	assert.True(t, removed)
+	api.AssertExpectations(t)
+}
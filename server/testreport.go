@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// maxListedTestCases limits the number of test cases listed in each section of a test summary.
+	maxListedTestCases = 10
+
+	// maxTestErrorLength truncates the error messages of failing test cases.
+	maxTestErrorLength = 200
+)
+
+// testReport is the test report of a build, as returned by the testReport API.
+type testReport struct {
+	FailCount int     `json:"failCount"`
+	PassCount int     `json:"passCount"`
+	SkipCount int     `json:"skipCount"`
+	Duration  float64 `json:"duration"`
+	Suites    []struct {
+		Cases []testCase `json:"cases"`
+	} `json:"suites"`
+}
+
+type testCase struct {
+	ClassName    string `json:"className"`
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	ErrorDetails string `json:"errorDetails"`
+}
+
+// FullName returns the name of the test case prefixed with its class name.
+func (c *testCase) FullName() string {
+	if c.ClassName == "" {
+		return c.Name
+	}
+	return c.ClassName + "." + c.Name
+}
+
+// Failed checks if the test case failed.
+func (c *testCase) Failed() bool {
+	return c.Status == "FAILED" || c.Status == "REGRESSION"
+}
+
+// Passed checks if the test case passed.
+func (c *testCase) Passed() bool {
+	return c.Status == "PASSED" || c.Status == "FIXED"
+}
+
+// Cases returns all the test cases of the report.
+func (r *testReport) Cases() []testCase {
+	cases := []testCase{}
+	for _, suite := range r.Suites {
+		cases = append(cases, suite.Cases...)
+	}
+	return cases
+}
+
+// FailedCases returns the failing test cases of the report.
+func (r *testReport) FailedCases() []testCase {
+	failed := []testCase{}
+	for _, c := range r.Cases() {
+		if c.Failed() {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// getTestReport fetches the test report of the build with the given API path.
+// Returns nil if the build has no test report.
+func getTestReport(jenkins *gojenkins.Jenkins, buildBase string) (*testReport, error) {
+	report := &testReport{}
+	query := map[string]string{"tree": "failCount,passCount,skipCount,duration,suites[cases[className,name,status,errorDetails]]"}
+	response, err := jenkins.Requester.GetJSON(buildBase+"/testReport", report, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the test report")
+	}
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status code %d while fetching the test report", response.StatusCode)
+	}
+
+	return report, nil
+}
+
+// testReportDelta compares the test reports of two builds and returns the names of the tests
+// which fail in the current build but didn't in the previous one, and the ones which failed
+// in the previous build and pass in the current one.
+func testReportDelta(previous, current *testReport) ([]string, []string) {
+	newlyFailing := []string{}
+	fixed := []string{}
+	if previous == nil || current == nil {
+		return newlyFailing, fixed
+	}
+
+	previousFailures := map[string]bool{}
+	for _, c := range previous.FailedCases() {
+		previousFailures[c.FullName()] = true
+	}
+
+	for _, c := range current.Cases() {
+		switch {
+		case c.Failed() && !previousFailures[c.FullName()]:
+			newlyFailing = append(newlyFailing, c.FullName())
+		case c.Passed() && previousFailures[c.FullName()]:
+			fixed = append(fixed, c.FullName())
+		}
+	}
+
+	sort.Strings(newlyFailing)
+	sort.Strings(fixed)
+	return newlyFailing, fixed
+}
+
+// testReportAttachment renders the test report of a build as an attachment, including the failing
+// test cases and the delta versus the previous build previousNumber, if its report is not nil.
+func testReportAttachment(jobName string, buildNumber int64, buildURL string, report, previous *testReport, previousNumber int64) *model.SlackAttachment {
+	text := fmt.Sprintf("Test results of the build #%d of the job '%s'\n[Test report](%stestReport)", buildNumber, jobName, buildURL)
+
+	if failed := report.FailedCases(); len(failed) > 0 {
+		text += "\n\n**Failing tests**"
+		for i, c := range failed {
+			if i == maxListedTestCases {
+				text += fmt.Sprintf("\n* and %d more", len(failed)-maxListedTestCases)
+				break
+			}
+
+			text += fmt.Sprintf("\n* `%s`", c.FullName())
+			if errorDetails := firstLine(c.ErrorDetails); errorDetails != "" {
+				if runes := []rune(errorDetails); len(runes) > maxTestErrorLength {
+					errorDetails = string(runes[:maxTestErrorLength]) + "..."
+				}
+				text += ": " + errorDetails
+			}
+		}
+	}
+
+	if previous != nil {
+		newlyFailing, fixed := testReportDelta(previous, report)
+		text += fmt.Sprintf("\n\n**Compared to the build #%d**", previousNumber)
+		text += "\n* Newly failing: " + formatTestNames(newlyFailing)
+		text += "\n* Fixed: " + formatTestNames(fixed)
+	}
+
+	attachment := generateSlackAttachment(text)
+	if report.FailCount > 0 {
+		attachment.Color = buildResultColor("FAILURE")
+	} else {
+		attachment.Color = buildResultColor("SUCCESS")
+	}
+
+	attachment.Fields = []*model.SlackAttachmentField{
+		{Title: "Passed", Value: strconv.Itoa(report.PassCount), Short: true},
+		{Title: "Failed", Value: strconv.Itoa(report.FailCount), Short: true},
+		{Title: "Skipped", Value: strconv.Itoa(report.SkipCount), Short: true},
+		{Title: "Duration", Value: formatDuration(int64(report.Duration * 1000)), Short: true},
+	}
+
+	return attachment
+}
+
+// formatTestNames formats a list of test names, limited to maxListedTestCases.
+func formatTestNames(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+
+	formatted := []string{}
+	for i, name := range names {
+		if i == maxListedTestCases {
+			formatted = append(formatted, fmt.Sprintf("and %d more", len(names)-maxListedTestCases))
+			break
+		}
+		formatted = append(formatted, "`"+name+"`")
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// firstLine returns the first non empty line of the text.
+func firstLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// getPreviousBuildNumber returns the number of the build which ran before the build, as reported by Jenkins.
+// Builds may have been deleted, so it isn't necessarily the number of the build minus one.
+// Returns 0 if there is no previous build.
+func getPreviousBuildNumber(build *gojenkins.Build) (int64, error) {
+	response := struct {
+		PreviousBuild *struct {
+			Number int64 `json:"number"`
+		} `json:"previousBuild"`
+	}{}
+	resp, err := build.Jenkins.Requester.GetJSON(build.Base, &response, map[string]string{"tree": "previousBuild[number]"})
+	if err != nil {
+		return 0, errors.Wrap(err, "Error fetching the previous build")
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, errors.Errorf("unexpected status code %d while fetching the previous build", resp.StatusCode)
+	}
+	if response.PreviousBuild == nil {
+		return 0, nil
+	}
+
+	return response.PreviousBuild.Number, nil
+}
+
+// postBuildTestResults posts a summary of the test report of a build, compared to the previous build.
+// The last build of the job is used if buildID is an empty string.
+func (p *Plugin) postBuildTestResults(userID, channelID, jobName, buildID string) error {
+	build, buildErr := p.getBuild(jobName, userID, buildID)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	instance := jobInstance(jobName)
+	report, err := getTestReport(build.Jenkins, build.Base)
+	if err != nil {
+		return err
+	}
+
+	if report == nil {
+		p.createPost(userID, channelID, instance, fmt.Sprintf("Build #%d of the job '%s' doesn't have test reports.", build.GetBuildNumber(), jobName))
+		return nil
+	}
+
+	var previous *testReport
+	previousNumber, err := getPreviousBuildNumber(build)
+	if err != nil {
+		p.API.LogWarn("Error fetching the previous build", "job_name", jobName, "err", err.Error())
+	} else if previousNumber != 0 {
+		previous, err = getTestReport(build.Jenkins, path.Dir(build.Base)+"/"+strconv.FormatInt(previousNumber, 10))
+		if err != nil {
+			p.API.LogWarn("Error fetching the test report of the previous build", "job_name", jobName, "err", err.Error())
+		}
+	}
+
+	p.createAttachmentPost(userID, channelID, instance, testReportAttachment(jobName, build.GetBuildNumber(), build.GetUrl(), report, previous, previousNumber))
+	return nil
+}
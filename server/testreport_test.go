@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waseem18/gojenkins"
+)
+
+func newTestReport(cases ...testCase) *testReport {
+	report := &testReport{}
+	report.Suites = append(report.Suites, struct {
+		Cases []testCase `json:"cases"`
+	}{Cases: cases})
+	for _, c := range cases {
+		switch {
+		case c.Failed():
+			report.FailCount++
+		case c.Passed():
+			report.PassCount++
+		default:
+			report.SkipCount++
+		}
+	}
+	return report
+}
+
+func TestTestReportDelta(t *testing.T) {
+	previous := newTestReport(
+		testCase{ClassName: "pkg.A", Name: "testOne", Status: "FAILED"},
+		testCase{ClassName: "pkg.A", Name: "testTwo", Status: "PASSED"},
+		testCase{ClassName: "pkg.B", Name: "testThree", Status: "FAILED"},
+	)
+	current := newTestReport(
+		testCase{ClassName: "pkg.A", Name: "testOne", Status: "FIXED"},
+		testCase{ClassName: "pkg.A", Name: "testTwo", Status: "REGRESSION"},
+		testCase{ClassName: "pkg.B", Name: "testThree", Status: "FAILED"},
+	)
+
+	newlyFailing, fixed := testReportDelta(previous, current)
+	assert.Equal(t, []string{"pkg.A.testTwo"}, newlyFailing)
+	assert.Equal(t, []string{"pkg.A.testOne"}, fixed)
+
+	newlyFailing, fixed = testReportDelta(nil, current)
+	assert.Empty(t, newlyFailing)
+	assert.Empty(t, fixed)
+}
+
+func TestTestReportAttachment(t *testing.T) {
+	previous := newTestReport(testCase{ClassName: "pkg.A", Name: "testOne", Status: "PASSED"})
+	report := newTestReport(
+		testCase{ClassName: "pkg.A", Name: "testOne", Status: "REGRESSION", ErrorDetails: "\nexpected 1 but was 2\nat pkg.A.testOne"},
+		testCase{ClassName: "pkg.A", Name: "testTwo", Status: "SKIPPED"},
+	)
+	report.Duration = 61.2
+
+	attachment := testReportAttachment("jobname", 42, "https://jenkins.example.com/job/jobname/42/", report, previous, 41)
+	assert.Contains(t, attachment.Text, "[Test report](https://jenkins.example.com/job/jobname/42/testReport)")
+	assert.Contains(t, attachment.Text, "* `pkg.A.testOne`: expected 1 but was 2\n")
+	assert.Contains(t, attachment.Text, "**Compared to the build #41**\n* Newly failing: `pkg.A.testOne`\n* Fixed: none")
+	assert.Equal(t, buildResultColor("FAILURE"), attachment.Color)
+
+	fields := map[string]string{}
+	for _, field := range attachment.Fields {
+		fields[field.Title] = field.Value.(string)
+	}
+	assert.Equal(t, map[string]string{"Passed": "0", "Failed": "1", "Skipped": "1", "Duration": "1m1s"}, fields)
+
+	attachment = testReportAttachment("jobname", 1, "https://jenkins.example.com/job/jobname/1/", newTestReport(), nil, 0)
+	assert.False(t, strings.Contains(attachment.Text, "Compared to"))
+}
+
+func TestGetTestReport(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/job/jobname/42/testReport/api/json" {
+			_, _ = res.Write([]byte(`{"failCount": 1, "passCount": 2, "skipCount": 0, "duration": 3.2,
+				"suites": [{"cases": [{"className": "pkg.A", "name": "testOne", "status": "FAILED", "errorDetails": "boom"}]}]}`))
+			return
+		}
+		if strings.HasSuffix(req.URL.Path, "/testReport/api/json") {
+			res.WriteHeader(http.StatusNotFound)
+			return
+		}
+		res.WriteHeader(http.StatusOK)
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	report, err := getTestReport(jenkins, "/job/jobname/42")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, report.FailCount)
+	assert.Equal(t, []testCase{{ClassName: "pkg.A", Name: "testOne", Status: "FAILED", ErrorDetails: "boom"}}, report.FailedCases())
+
+	report, err = getTestReport(jenkins, "/job/jobname/41")
+	assert.Nil(t, err)
+	assert.Nil(t, report)
+}
+
+func TestGetPreviousBuildNumber(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/job/jobname/42/api/json":
+			_, _ = res.Write([]byte(`{"previousBuild": {"number": 39}}`))
+		case "/job/jobname/1/api/json":
+			_, _ = res.Write([]byte(`{"previousBuild": null}`))
+		case "/job/secret/3/api/json":
+			res.WriteHeader(http.StatusForbidden)
+		default:
+			res.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+
+	p, _ := setupTestPlugin(t, testServer.URL)
+	jenkins, err := p.getJenkinsClient("user1", "")
+	assert.Nil(t, err)
+
+	for base, expected := range map[string]int64{
+		"/job/jobname/42": 39,
+		"/job/jobname/1":  0,
+		"/job/deleted/7":  0,
+	} {
+		number, err := getPreviousBuildNumber(&gojenkins.Build{Jenkins: jenkins, Base: base})
+		assert.Nil(t, err)
+		assert.Equal(t, expected, number, base)
+	}
+
+	_, err = getPreviousBuildNumber(&gojenkins.Build{Jenkins: jenkins, Base: "/job/secret/3"})
+	assert.NotNil(t, err)
+}
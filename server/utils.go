@@ -10,9 +10,12 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 )
@@ -88,17 +91,38 @@ func decrypt(key []byte, text string) (string, error) {
 // The third return value is a map containing the key=value parameters.
 // The last boolean return value indicates if the parsing was successful.
 func parseBuildParameters(parameters []string) (string, string, map[string]string, bool) {
-	if len(parameters) == 0 {
+	jobName, rest, ok := splitJobName(parameters)
+	if !ok {
 		return "", "", nil, false
 	}
 
-	// Handle the job name (which might be quoted with spaces)
+	// Process build number and parameters
+	buildNumber := ""
+	paramIndex := 0
+
+	// Check if the next parameter is a build number (numeric)
+	if paramIndex < len(rest) && isNumeric(rest[paramIndex]) {
+		buildNumber = rest[paramIndex]
+		paramIndex++
+	}
+
+	return jobName, buildNumber, parseKeyValueParameters(rest[paramIndex:]), true
+}
+
+// splitJobName extracts the job name, which might be quoted with spaces, from the parameters
+// and returns it along with the remaining parameters.
+// The last boolean return value indicates if the parsing was successful.
+func splitJobName(parameters []string) (string, []string, bool) {
+	if len(parameters) == 0 {
+		return "", nil, false
+	}
+
 	jobNameParts := []string{}
 	paramIndex := 0
 
 	// Collect job name parts until we find a closing quote or a non-quoted parameter
 	inQuotes := false
-	if len(parameters) > 0 && strings.HasPrefix(parameters[0], "\"") {
+	if strings.HasPrefix(parameters[0], "\"") {
 		inQuotes = true
 		// Remove the starting quote
 		parameters[0] = strings.TrimPrefix(parameters[0], "\"")
@@ -114,7 +138,7 @@ func parseBuildParameters(parameters []string) (string, string, map[string]strin
 			jobNameParts = append(jobNameParts, parameters[0])
 			paramIndex = 1
 		}
-	} else if len(parameters) > 0 {
+	} else {
 		// Simple non-quoted job name
 		jobNameParts = append(jobNameParts, parameters[0])
 		paramIndex = 1
@@ -135,22 +159,16 @@ func parseBuildParameters(parameters []string) (string, string, map[string]strin
 		}
 	}
 
-	jobName := strings.Join(jobNameParts, " ")
+	return strings.Join(jobNameParts, " "), parameters[paramIndex:], true
+}
 
-	// Process build number and parameters
-	buildNumber := ""
+// parseKeyValueParameters returns a map of the key=value parameters.
+// Parameters which are not in the key=value format are ignored.
+func parseKeyValueParameters(parameters []string) map[string]string {
 	var paramMap map[string]string
-
-	// Check if the next parameter is a build number (numeric)
-	if paramIndex < len(parameters) && isNumeric(parameters[paramIndex]) {
-		buildNumber = parameters[paramIndex]
-		paramIndex++
-	}
-
-	// Process key=value parameters
-	for i := paramIndex; i < len(parameters); i++ {
-		if strings.Contains(parameters[i], "=") {
-			parts := strings.SplitN(parameters[i], "=", 2)
+	for _, parameter := range parameters {
+		if strings.Contains(parameter, "=") {
+			parts := strings.SplitN(parameter, "=", 2)
 			if len(parts) == 2 {
 				if paramMap == nil {
 					paramMap = make(map[string]string)
@@ -159,8 +177,105 @@ func parseBuildParameters(parameters []string) (string, string, map[string]strin
 			}
 		}
 	}
+	return paramMap
+}
+
+// parseJobSelector splits a job selector in the instance:folder1/jobname format
+// into the name of the Jenkins instance and the job name.
+// The instance name is empty when the selector has no instance prefix.
+func parseJobSelector(selector string) (string, string) {
+	// Colons are not allowed in Jenkins job names, so the first one separates the instance.
+	if i := strings.Index(selector, ":"); i > 0 {
+		return selector[:i], selector[i+1:]
+	}
+	return "", selector
+}
+
+// jobInstance returns the name of the Jenkins instance of a job selector.
+func jobInstance(selector string) string {
+	instance, _ := parseJobSelector(selector)
+	return instance
+}
+
+// qualifyJobName prefixes the job name with the given Jenkins instance,
+// unless the instance is empty or the job name already has an instance prefix.
+func qualifyJobName(instance, jobName string) string {
+	if instance == "" || strings.Contains(jobName, ":") {
+		return jobName
+	}
+	return instance + ":" + jobName
+}
+
+// qualifyJobSelector qualifies a job name typed by the user with the instance given with --instance.
+// Returns false if the job name has an instance prefix which doesn't match the given instance.
+func qualifyJobSelector(instance, jobName string) (string, bool) {
+	if selectorInstance := jobInstance(jobName); instance != "" && selectorInstance != "" && selectorInstance != instance {
+		return jobName, false
+	}
+	return qualifyJobName(instance, jobName), true
+}
 
-	return jobName, buildNumber, paramMap, true
+// instanceMismatchResponse is the response to a job name whose instance prefix doesn't match --instance.
+func instanceMismatchResponse(instance, jobName string) string {
+	return fmt.Sprintf("The job '%s' is on the Jenkins instance '%s', which doesn't match `--instance %s`.", jobName, jobInstance(jobName), instance)
+}
+
+// extractFlag removes the --name flag and its value from the parameters.
+// It returns the remaining parameters and the value of the flag, which is empty if the flag is not present.
+// The last boolean return value is false if the flag has no value.
+func extractFlag(parameters []string, name string) ([]string, string, bool) {
+	flag := "--" + name
+	remaining := []string{}
+	value := ""
+	for i := 0; i < len(parameters); i++ {
+		switch {
+		case parameters[i] == flag:
+			if i+1 >= len(parameters) {
+				return nil, "", false
+			}
+			value = parameters[i+1]
+			i++
+		case strings.HasPrefix(parameters[i], flag+"="):
+			value = strings.TrimPrefix(parameters[i], flag+"=")
+			if value == "" {
+				return nil, "", false
+			}
+		default:
+			remaining = append(remaining, parameters[i])
+		}
+	}
+	return remaining, value, true
+}
+
+// extractBoolFlag removes the --name flag, which takes no value, from the parameters.
+// It returns the remaining parameters and whether the flag was present.
+func extractBoolFlag(parameters []string, name string) ([]string, bool) {
+	remaining := []string{}
+	present := false
+	for _, parameter := range parameters {
+		if parameter == "--"+name {
+			present = true
+			continue
+		}
+		remaining = append(remaining, parameter)
+	}
+	return remaining, present
+}
+
+// escapeTableCell escapes a value to be displayed in a cell of a markdown table.
+func escapeTableCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.Join(strings.Fields(value), " ")
+}
+
+// containsString checks if the slice contains the given string.
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
 }
 
 // Helper function to check if a string is numeric
@@ -176,3 +291,109 @@ func generateSlackAttachment(text string) *model.SlackAttachment {
 	}
 	return slackAttachment
 }
+
+// buildResultColor returns the attachment color matching a Jenkins build result.
+func buildResultColor(result string) string {
+	switch result {
+	case "SUCCESS":
+		return "#3DB887"
+	case "FAILURE":
+		return "#D24B4E"
+	case "UNSTABLE":
+		return "#FFBC1F"
+	case "ABORTED", "NOT_BUILT":
+		return "#8C8C8C"
+	default:
+		return "#7FC1EE"
+	}
+}
+
+// formatDuration formats a duration given in milliseconds, as reported by Jenkins.
+func formatDuration(milliseconds int64) string {
+	return (time.Duration(milliseconds) * time.Millisecond).Round(time.Second).String()
+}
+
+// dialogElementForParameter maps a Jenkins build parameter to the matching interactive dialog element,
+// prefilled with the default value of the parameter.
+func dialogElementForParameter(parameter jobParameterDefinition) model.DialogElement {
+	element := model.DialogElement{
+		DisplayName: parameter.Name,
+		Name:        parameter.Name,
+		HelpText:    parameter.Description,
+		Type:        "text",
+		SubType:     "text",
+		Optional:    true,
+	}
+
+	defaultValue := ""
+	if parameter.DefaultParameterValue.Value != nil {
+		defaultValue = fmt.Sprint(parameter.DefaultParameterValue.Value)
+	}
+
+	switch parameter.Type {
+	case "BooleanParameterDefinition":
+		element.Type = "bool"
+		element.SubType = ""
+		element.Default = strconv.FormatBool(defaultValue == "true")
+	case "ChoiceParameterDefinition":
+		element.Type = "select"
+		element.SubType = ""
+		element.Optional = false
+		for _, choice := range parameter.Choices {
+			element.Options = append(element.Options, &model.PostActionOptions{Text: choice, Value: choice})
+		}
+		element.Default = defaultValue
+	case "TextParameterDefinition":
+		element.Type = "textarea"
+		element.Default = defaultValue
+	case "PasswordParameterDefinition":
+		// Jenkins doesn't expose the default value of password parameters.
+		element.SubType = "password"
+	default:
+		element.Default = defaultValue
+	}
+
+	return element
+}
+
+// dialogSubmissionToParameters converts the values submitted in an interactive dialog
+// to the string parameters expected by Jenkins. Unset and empty values are left out so that Jenkins uses the defaults.
+func dialogSubmissionToParameters(submission map[string]interface{}) map[string]string {
+	parameters := make(map[string]string)
+	for name, value := range submission {
+		switch v := value.(type) {
+		case string:
+			if v != "" {
+				parameters[name] = v
+			}
+		case bool:
+			parameters[name] = strconv.FormatBool(v)
+		case float64:
+			parameters[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case nil:
+		default:
+			parameters[name] = fmt.Sprint(v)
+		}
+	}
+	return parameters
+}
+
+// jobNameFromURL extracts the full job name from a Jenkins job or build URL.
+// For example, "job/folder1/job/jobname/42/" returns "folder1/jobname".
+func jobNameFromURL(jobURL string) string {
+	u, err := url.Parse(jobURL)
+	if err != nil {
+		return ""
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	jobNameParts := []string{}
+	for i := 0; i < len(segments)-1; i++ {
+		if segments[i] == "job" {
+			jobNameParts = append(jobNameParts, segments[i+1])
+			i++
+		}
+	}
+
+	return strings.Join(jobNameParts, "/")
+}
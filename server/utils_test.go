@@ -92,3 +92,168 @@ func TestParseBuildParameters(t *testing.T) {
 		})
 	}
 }
+
+func TestJobNameFromURL(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Input    string
+		Expected string
+	}{
+		"relative job URL": {
+			Input:    "job/jobname/",
+			Expected: "jobname",
+		},
+		"relative build URL in folder": {
+			Input:    "job/folder1/job/jobname/42/",
+			Expected: "folder1/jobname",
+		},
+		"absolute build URL": {
+			Input:    "https://jenkins.example.com/job/folder1/job/jobname/42/",
+			Expected: "folder1/jobname",
+		},
+		"with context path and spaces": {
+			Input:    "https://example.com/jenkins/job/folder%20with%20spaces/job/jobname/",
+			Expected: "folder with spaces/jobname",
+		},
+		"not a job URL": {
+			Input:    "https://jenkins.example.com/queue/",
+			Expected: "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.Expected, jobNameFromURL(tc.Input))
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "0s", formatDuration(0))
+	assert.Equal(t, "2s", formatDuration(1600))
+	assert.Equal(t, "1m5s", formatDuration(65000))
+	assert.Equal(t, "1h0m0s", formatDuration(3600000))
+}
+
+func TestParseJobSelector(t *testing.T) {
+	instance, jobName := parseJobSelector("folder1/jobname")
+	assert.Equal(t, "", instance)
+	assert.Equal(t, "folder1/jobname", jobName)
+
+	instance, jobName = parseJobSelector("ci:folder1/jobname")
+	assert.Equal(t, "ci", instance)
+	assert.Equal(t, "folder1/jobname", jobName)
+
+	assert.Equal(t, "ci:jobname", qualifyJobName("ci", "jobname"))
+	assert.Equal(t, "release:jobname", qualifyJobName("ci", "release:jobname"))
+	assert.Equal(t, "jobname", qualifyJobName("", "jobname"))
+
+	for name, tc := range map[string]struct {
+		Instance   string
+		JobName    string
+		Expected   string
+		ExpectedOk bool
+	}{
+		"no instance":       {Instance: "", JobName: "release:jobname", Expected: "release:jobname", ExpectedOk: true},
+		"no prefix":         {Instance: "ci", JobName: "jobname", Expected: "ci:jobname", ExpectedOk: true},
+		"matching prefix":   {Instance: "ci", JobName: "ci:jobname", Expected: "ci:jobname", ExpectedOk: true},
+		"different prefix":  {Instance: "ci", JobName: "release:jobname", Expected: "release:jobname", ExpectedOk: false},
+		"folder and prefix": {Instance: "ci", JobName: "release:folder1/jobname", Expected: "release:folder1/jobname", ExpectedOk: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			jobName, ok := qualifyJobSelector(tc.Instance, tc.JobName)
+			assert.Equal(t, tc.Expected, jobName)
+			assert.Equal(t, tc.ExpectedOk, ok)
+		})
+	}
+	assert.Equal(t, "The job 'release:jobname' is on the Jenkins instance 'release', which doesn't match `--instance ci`.", instanceMismatchResponse("ci", "release:jobname"))
+}
+
+func TestExtractFlag(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Input              []string
+		ExpectedParameters []string
+		ExpectedInstance   string
+		Valid              bool
+	}{
+		"no flag": {
+			Input:              []string{"jobname", "22"},
+			ExpectedParameters: []string{"jobname", "22"},
+			Valid:              true,
+		},
+		"flag with separate value": {
+			Input:              []string{"jobname", "--instance", "ci", "22"},
+			ExpectedParameters: []string{"jobname", "22"},
+			ExpectedInstance:   "ci",
+			Valid:              true,
+		},
+		"flag with inline value": {
+			Input:              []string{"--instance=ci", "jobname"},
+			ExpectedParameters: []string{"jobname"},
+			ExpectedInstance:   "ci",
+			Valid:              true,
+		},
+		"flag without value": {
+			Input: []string{"jobname", "--instance"},
+			Valid: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			parameters, instance, ok := extractFlag(tc.Input, "instance")
+			assert.Equal(t, tc.Valid, ok)
+			if tc.Valid {
+				assert.Equal(t, tc.ExpectedParameters, parameters)
+				assert.Equal(t, tc.ExpectedInstance, instance)
+			}
+		})
+	}
+}
+
+func TestDialogElementForParameter(t *testing.T) {
+	newParameter := func(parameterType string, defaultValue interface{}) jobParameterDefinition {
+		parameter := jobParameterDefinition{Name: "param", Type: parameterType, Description: "description"}
+		parameter.DefaultParameterValue.Value = defaultValue
+		return parameter
+	}
+
+	element := dialogElementForParameter(newParameter("StringParameterDefinition", "value"))
+	assert.Equal(t, "text", element.Type)
+	assert.Equal(t, "text", element.SubType)
+	assert.Equal(t, "value", element.Default)
+	assert.Equal(t, "description", element.HelpText)
+
+	element = dialogElementForParameter(newParameter("BooleanParameterDefinition", true))
+	assert.Equal(t, "bool", element.Type)
+	assert.Equal(t, "true", element.Default)
+
+	choiceParameter := newParameter("ChoiceParameterDefinition", "staging")
+	choiceParameter.Choices = []string{"staging", "production"}
+	element = dialogElementForParameter(choiceParameter)
+	assert.Equal(t, "select", element.Type)
+	assert.Equal(t, "staging", element.Default)
+	assert.Len(t, element.Options, 2)
+	assert.Equal(t, "production", element.Options[1].Value)
+	assert.False(t, element.Optional)
+
+	element = dialogElementForParameter(newParameter("TextParameterDefinition", "line1\nline2"))
+	assert.Equal(t, "textarea", element.Type)
+	assert.Equal(t, "line1\nline2", element.Default)
+
+	element = dialogElementForParameter(newParameter("PasswordParameterDefinition", "<DEFAULT>"))
+	assert.Equal(t, "text", element.Type)
+	assert.Equal(t, "password", element.SubType)
+	assert.Equal(t, "", element.Default)
+}
+
+func TestDialogSubmissionToParameters(t *testing.T) {
+	parameters := dialogSubmissionToParameters(map[string]interface{}{
+		"string": "value",
+		"bool":   false,
+		"number": float64(3),
+		"unset":  nil,
+		"empty":  "",
+	})
+	assert.Equal(t, map[string]string{"string": "value", "bool": "false", "number": "3"}, parameters)
+}
+
+func TestEscapeTableCell(t *testing.T) {
+	assert.Equal(t, `Waiting for next available executor on agent\|linux`, escapeTableCell("Waiting for next available executor on agent|linux"))
+	assert.Equal(t, "line1 line2", escapeTableCell("line1\n  line2\n"))
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	phaseQueued    = "QUEUED"
+	phaseStarted   = "STARTED"
+	phaseCompleted = "COMPLETED"
+	phaseFinalized = "FINALIZED"
+
+	// maxWebhookBodySize limits the size of the notifications, which only take a few kilobytes.
+	maxWebhookBodySize = 1024 * 1024
+)
+
+// jenkinsNotification is the payload sent by the Jenkins Notification plugin.
+type jenkinsNotification struct {
+	Name  string                   `json:"name"`
+	URL   string                   `json:"url"`
+	Build jenkinsNotificationBuild `json:"build"`
+
+	// Instance is the name of the Jenkins instance which sent the notification.
+	Instance string `json:"-"`
+}
+
+type jenkinsNotificationBuild struct {
+	FullURL string `json:"full_url"`
+	Number  int64  `json:"number"`
+	Phase   string `json:"phase"`
+	Status  string `json:"status"`
+	URL     string `json:"url"`
+}
+
+// IsValid checks that the notification carries everything needed to create a post.
+func (n *jenkinsNotification) IsValid() error {
+	if n.Name == "" && n.URL == "" {
+		return errors.New("job name is missing")
+	}
+
+	if n.Build.Number <= 0 {
+		return errors.New("build number is missing")
+	}
+
+	switch n.Build.Phase {
+	case phaseQueued, phaseStarted, phaseCompleted, phaseFinalized:
+	default:
+		return errors.Errorf("unknown build phase %q", n.Build.Phase)
+	}
+
+	if n.Build.Phase == phaseCompleted && n.Build.Status == "" {
+		return errors.New("build status is missing")
+	}
+
+	return nil
+}
+
+// JobName returns the full path of the job, including its folders,
+// prefixed with the Jenkins instance if the notification was not sent by the default instance.
+func (n *jenkinsNotification) JobName() string {
+	jobName := jobNameFromURL(n.URL)
+	if jobName == "" {
+		jobName = n.Name
+	}
+	return qualifyJobName(n.Instance, jobName)
+}
+
+// Event returns the subscription event matching the notification.
+// Returns an empty string if the notification doesn't match any event.
+func (n *jenkinsNotification) Event() string {
+	switch n.Build.Phase {
+	case phaseStarted:
+		return eventStarted
+	case phaseCompleted:
+		return strings.ToLower(n.Build.Status)
+	default:
+		return ""
+	}
+}
+
+// handleWebhook receives build events from the Jenkins Notification plugin
+// and posts them to the subscribed channels and to the channel given in the request, if any.
+func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	config := p.getConfiguration()
+	if config.WebhookSecret == "" {
+		http.Error(w, "Webhook secret is not configured.", http.StatusForbidden)
+		return
+	}
+
+	secret := r.URL.Query().Get("secret")
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(config.WebhookSecret)) != 1 {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	instance := r.URL.Query().Get("instance")
+	if instance != "" {
+		if _, err := config.getInstanceURL(instance); err != nil {
+			http.Error(w, "Unknown Jenkins instance", http.StatusBadRequest)
+			return
+		}
+	}
+
+	notification := jenkinsNotification{Instance: instance}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookBodySize)).Decode(&notification); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := notification.IsValid(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if notificationAttachment(&notification) == nil {
+		return
+	}
+
+	channelIDs, err := p.getSubscribedChannels(notification.JobName(), notification.Event())
+	if err != nil {
+		p.API.LogError("Error fetching subscriptions", "err", err.Error())
+		http.Error(w, "Error fetching subscriptions", http.StatusInternalServerError)
+		return
+	}
+
+	if channelID := r.URL.Query().Get("channel_id"); channelID != "" {
+		if _, appErr := p.API.GetChannel(channelID); appErr != nil {
+			http.Error(w, "Channel not found", http.StatusBadRequest)
+			return
+		}
+
+		if !containsString(channelIDs, channelID) {
+			channelIDs = append(channelIDs, channelID)
+		}
+	}
+
+	for _, channelID := range channelIDs {
+		p.createBotPost(channelID, notificationAttachment(&notification))
+	}
+}
+
+// notificationAttachment renders a build event as an attachment.
+// Returns nil for phases that should not be posted.
+func notificationAttachment(notification *jenkinsNotification) *model.SlackAttachment {
+	jobName := notification.JobName()
+	build := notification.Build
+
+	var attachment *model.SlackAttachment
+	switch build.Phase {
+	case phaseStarted:
+		attachment = generateSlackAttachment(fmt.Sprintf("Job '%s' - #%d has been started\nBuild URL : %s", jobName, build.Number, build.FullURL))
+	case phaseCompleted:
+		attachment = generateSlackAttachment(fmt.Sprintf("Job '%s' - #%d has finished with status %s\nBuild URL : %s", jobName, build.Number, build.Status, build.FullURL))
+		attachment.Color = buildResultColor(build.Status)
+	default:
+		return nil
+	}
+
+	return attachment
+}
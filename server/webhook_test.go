@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestJenkinsNotificationIsValid(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Notification jenkinsNotification
+		Valid        bool
+	}{
+		"started": {
+			Notification: jenkinsNotification{Name: "job", Build: jenkinsNotificationBuild{Number: 1, Phase: phaseStarted}},
+			Valid:        true,
+		},
+		"completed": {
+			Notification: jenkinsNotification{Name: "job", Build: jenkinsNotificationBuild{Number: 1, Phase: phaseCompleted, Status: "SUCCESS"}},
+			Valid:        true,
+		},
+		"completed without status": {
+			Notification: jenkinsNotification{Name: "job", Build: jenkinsNotificationBuild{Number: 1, Phase: phaseCompleted}},
+			Valid:        false,
+		},
+		"missing job name": {
+			Notification: jenkinsNotification{Build: jenkinsNotificationBuild{Number: 1, Phase: phaseStarted}},
+			Valid:        false,
+		},
+		"missing build number": {
+			Notification: jenkinsNotification{Name: "job", Build: jenkinsNotificationBuild{Phase: phaseStarted}},
+			Valid:        false,
+		},
+		"unknown phase": {
+			Notification: jenkinsNotification{Name: "job", Build: jenkinsNotificationBuild{Number: 1, Phase: "DONE"}},
+			Valid:        false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := tc.Notification.IsValid()
+			assert.Equal(t, tc.Valid, err == nil)
+		})
+	}
+}
+
+func TestHandleWebhook(t *testing.T) {
+	const body = `{"name":"jobname","url":"job/folder1/job/jobname/","build":{"full_url":"https://jenkins.example.com/job/folder1/job/jobname/42/","number":42,"phase":"COMPLETED","status":"FAILURE"}}`
+
+	setupPlugin := func() (*Plugin, *plugintest.API) {
+		p := &Plugin{botUserID: "bot"}
+		api := &plugintest.API{}
+		p.SetAPI(api)
+		p.setConfiguration(&configuration{WebhookSecret: "secret"}, &model.Config{})
+		return p, api
+	}
+
+	t.Run("wrong secret", func(t *testing.T) {
+		p, api := setupPlugin()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhook?secret=wrong&channel_id=channel1", strings.NewReader(body))
+		p.handleWebhook(w, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		p, api := setupPlugin()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhook?secret=secret&channel_id=channel1", strings.NewReader(`{"name":"jobname"}`))
+		p.handleWebhook(w, r)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("payload too large", func(t *testing.T) {
+		p, api := setupPlugin()
+		large := `{"name":"jobname","build":{"log":"` + strings.Repeat("x", maxWebhookBodySize) + `"}}`
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhook?secret=secret&channel_id=channel1", strings.NewReader(large))
+		p.handleWebhook(w, r)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		api.AssertNotCalled(t, "CreatePost", mock.Anything)
+	})
+
+	t.Run("posts the build result", func(t *testing.T) {
+		p, api := setupPlugin()
+		api.On("KVGet", jenkinsSubscriptionsKey).Return(nil, nil)
+		api.On("GetChannel", "channel1").Return(&model.Channel{Id: "channel1"}, nil)
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			attachments := post.Props["attachments"].([]*model.SlackAttachment)
+			return post.ChannelId == "channel1" &&
+				strings.Contains(attachments[0].Text, "Job 'folder1/jobname' - #42 has finished with status FAILURE") &&
+				attachments[0].Color == buildResultColor("FAILURE")
+		})).Return(&model.Post{}, nil)
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhook?secret=secret&channel_id=channel1", strings.NewReader(body))
+		p.handleWebhook(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		api.AssertExpectations(t)
+	})
+
+	t.Run("posts to subscribed channels", func(t *testing.T) {
+		p, api := setupPlugin()
+		subscriptions, err := json.Marshal(&Subscriptions{Channels: map[string][]*Subscription{
+			"channel2": {{ChannelID: "channel2", Job: "folder1/*", Events: []string{eventFailure}}},
+			"channel3": {{ChannelID: "channel3", Job: "folder1/jobname", Events: []string{eventSuccess}}},
+		}})
+		assert.Nil(t, err)
+		api.On("KVGet", jenkinsSubscriptionsKey).Return(subscriptions, nil)
+		api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
+			return post.ChannelId == "channel2"
+		})).Return(&model.Post{}, nil).Once()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/webhook?secret=secret", strings.NewReader(body))
+		p.handleWebhook(w, r)
+		assert.Equal(t, http.StatusOK, w.Code)
+		api.AssertExpectations(t)
+	})
+}